@@ -9,9 +9,9 @@ import (
 
 	"github.com/MisustinIvan/ilang/internal/ast_visualizer"
 	"github.com/MisustinIvan/ilang/internal/code_generator"
-	"github.com/MisustinIvan/ilang/internal/lexer"
+	"github.com/MisustinIvan/ilang/internal/lifetime_checker"
+	"github.com/MisustinIvan/ilang/internal/module_loader"
 	"github.com/MisustinIvan/ilang/internal/name_resolver"
-	"github.com/MisustinIvan/ilang/internal/parser"
 	"github.com/MisustinIvan/ilang/internal/type_checker"
 	"github.com/MisustinIvan/ilang/internal/type_resolver"
 )
@@ -35,6 +35,7 @@ func main() {
 	dumpAssembly := flag.String("s", "", "write generated assembly to file")
 	dumpTokens := flag.String("t", "", "write token dump to file")
 	dumpAst := flag.String("a", "", "write AST dot graph to file")
+	boundsChecks := flag.Bool("b", false, "check array and slice indexes at runtime, slice bounds are always checked")
 	flag.Parse()
 
 	if *inputPath == "" || *help {
@@ -42,42 +43,41 @@ func main() {
 		os.Exit(1)
 	}
 
-	src, err := lexer.ReadFile(*inputPath)
+	loader := module_loader.New(*inputPath)
+	program, err := loader.Load()
 	if err != nil {
-		fail(fmt.Errorf("could not read %q: %v", *inputPath, err))
-	}
-
-	l := lexer.New(*src)
-	tokens, err := l.Lex()
-	if err != nil {
-		fail(fmt.Errorf("lex: %v", err))
+		fail(err)
 	}
 
 	if *dumpTokens != "" {
 		var out strings.Builder
-		for _, token := range tokens {
+		for _, token := range loader.Tokens() {
 			out.WriteString(token.String() + "\n")
 		}
 		writeFile(*dumpTokens, out.String())
 		fmt.Printf("tokens written to %q\n", *dumpTokens)
 	}
 
-	program, err := parser.New(tokens).Parse()
+	program, err = name_resolver.NewResolver(program).ResolveNames()
 	if err != nil {
 		fail(err)
 	}
 
-	program, err = name_resolver.NewResolver(program).ResolveNames()
+	program, err = type_resolver.NewResolver(program).ResolveTypes()
 	if err != nil {
 		fail(err)
 	}
 
-	program, err = type_resolver.NewResolver(program).ResolveTypes()
+	program, err = type_checker.NewChecker(program).CheckTypes()
 	if err != nil {
 		fail(err)
 	}
 
-	program, err = type_checker.NewChecker(program).CheckTypes()
+	lifetimeChecker := lifetime_checker.NewChecker(program)
+	program, err = lifetimeChecker.CheckLifetimes()
+	for _, warning := range lifetimeChecker.Warnings() {
+		fmt.Println(warning)
+	}
 	if err != nil {
 		fail(err)
 	}
@@ -91,7 +91,9 @@ func main() {
 		fmt.Printf("AST written to %q\n", *dumpAst)
 	}
 
-	assembly, err := code_generator.New(program).Generate()
+	generator := code_generator.New(program)
+	generator.BoundsChecks = *boundsChecks
+	assembly, err := generator.Generate()
 	if err != nil {
 		fail(err)
 	}
@@ -361,7 +361,7 @@ Níže je vypsaná formální gramatika jazyka ve formě ekvivalentí s rozší
       #syntax-rule(
         meta-id: [call],
         definition-list: ([
-          #single-definition[identifier]
+          #single-definition[primary]
           #terminal(illumination: "highlighted")[(]
           #single-definition[value]
           #repeated-sequence([
@@ -592,6 +592,7 @@ Jazyk má pro proměnné jediný rozsah platnosti, a to v těle funkce. Všechny
 
 == Hodnoty a výrazy
 Každý výraz vrací hodnotu. Bloky *{ ... }* vrací hodnotu posledního výrazu v těle, pokud za ním není středník *;*. V případě, že poslední výraz za sebou středník *;* má, vrací *0*. Podmínka *if* vrací hodnotu větve, která byla vyhodnocena. Cyklus *for* vrací poslední hodnotu těla, nebo *0* pokud tělo neproběhlo ani jednou.
+Logické operátory *&&* a *||* se vyhodnocují zkráceně. Pravý operand operátoru *&&* se vyhodnotí jen tehdy, když je levý *true*, a pravý operand operátoru *||* jen tehdy, když je levý *false*. Výraz *i < len(s) && s[i] == 0* tedy nikdy neindexuje mimo rozsah.
 
 == Správa paměti
 Pole(arrays) jsou alokována na zásobníku a jejich velikost musí být známa při překladu. Dynamická pole(slices) jsou buďto odkazy na pole na zásobníku(tak jsou pole předávána do funkcí) nebo jsou alokována na haldě pomocí *make(T, N)*, kde *T* je základní typ a *N* je počet prvků, který nemusí být při překladu známý. Vestavěná funkce *make* je abstrakcí nad funkcí z libc *malloc*. Správa paměti je plně na uživateli, a tak musí alokovanou paměť dealokovat pomocí vestavěné funkce *relase(S)* kde *S* je identifikátor s typem *slice*. Vestavěná funkce *release* je abstrakcí nad funckcí z libc *free*.
@@ -600,7 +601,7 @@ Pole(arrays) jsou alokována na zásobníku a jejich velikost musí být známa
 Argumenty jsou předávány hodnotou. Pole a odkazy na pole jsou předávány jako dvojice (*odkaz* *délka*). Úpravy prvků pole uvnitř funkce se tedy projeví i mimo ni, přiřazení pole ale nikoliv. To jenom upraví hodnotu odkazu a délky v lokální proměnné. Výjimka je pro argumenty s typem pole, kde známe délku při překladu. V tom případě dojde při přiřazení k hodnotě se stejným typem k překopírování prvků.
 
 == Precedence operátorů
-Precedence operátorů není přímo definována gramatikou, ale následující tabulkou:
+Precedence operátorů není přímo definována gramatikou, ale následující tabulkou. Bitové operátory mají stejně jako v jazyce C nižší precedenci než porovnání, výraz *a & m == 0* se tedy vyhodnotí jako *a & (m == 0)*.
 
 #grid(
   columns: 2,
@@ -610,16 +611,19 @@ Precedence operátorů není přímo definována gramatikou, ale následující
   align: horizon,
   [1], [||],
   [2], [&&],
-  [3], [==, !=],
-  [4], [<, >, <=, >=],
-  [5], [+, -],
-  [6], [\*, /],
-  [7], [<<, >>],
+  [3], [|],
+  [4], [^],
+  [5], [&],
+  [6], [==, !=],
+  [7], [<, >, <=, >=],
+  [8], [+, -],
+  [9], [\*, /, %],
+  [10], [<<, >>],
 )
 
 == Omezení
-Logické operátory nepodporují zkrácené vyhodnocení, vždy se vyhodnotí celý výraz.
-Indexování je povoleno jenom pro hodnoty typu *slice* nebo *array*, ne pro hodnoty typu *string*.
+Znaky hodnot typu *string* lze indexovat jenom pro čtení. Převod *[]u8(S)* proto znaky řetězce zkopíruje do nové alokace na haldě, kterou je třeba uvolnit pomocí *release*.
+Převod *string(B)* znaky nekopíruje a předpokládá, že je mezi nimi nulový znak. Pokud chybí a kontrola mezí(*-b*) je vypnutá, čte se za koncem pole.
 Koerce typů je podporována jen pro implicitní převod hodnoty typu *array* na odkaz typu *slice*.
 
 #pagebreak()
@@ -3,6 +3,7 @@ package ast
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/MisustinIvan/ilang/internal/lexer"
 )
@@ -12,12 +13,21 @@ type (
 		VisitProgram(p *Program) error
 		VisitDeclaration(d *Declaration) error
 		VisitExternalDeclaration(d *ExternalDeclaration) error
+		VisitStructDeclaration(d *StructDeclaration) error
+		VisitEnumDeclaration(d *EnumDeclaration) error
+		VisitGlobal(g *Global) error
+		VisitConstant(c *Constant) error
 		VisitArgument(a *Argument) error
 		VisitBasicType(t *BasicType) error
 		VisitArrayType(t *ArrayType) error
 		VisitSliceType(t *SliceType) error
 		VisitPointerType(t *PointerType) error
+		VisitStructType(t *StructType) error
+		VisitEnumType(t *EnumType) error
+		VisitFunctionType(t *FunctionType) error
 		VisitReturn(r *Return) error
+		VisitBreak(b *Break) error
+		VisitContinue(c *Continue) error
 		VisitBind(b *Bind) error
 		VisitLiteral(l *Literal) error
 		VisitIdentifier(i *Identifier) error
@@ -28,25 +38,62 @@ type (
 		VisitBlock(b *Block) error
 		VisitCondition(c *Condition) error
 		VisitIndex(i *Index) error
+		VisitSlice(s *Slice) error
 		VisitAssignment(a *Assignment) error
 		VisitArrayLiteral(a *ArrayLiteral) error
 		VisitDereference(d *Dereference) error
 		VisitLoop(l *Loop) error
 		VisitMake(m *Make) error
 		VisitRelease(r *Release) error
+		VisitFieldAccess(f *FieldAccess) error
+		VisitConversion(c *Conversion) error
+		VisitLength(l *Length) error
+		VisitAppend(a *Append) error
+		VisitCopy(c *Copy) error
+		VisitVariantAccess(v *VariantAccess) error
+		VisitMatch(m *Match) error
 	}
 
 	Node interface{ Accept(Visitor) error }
 )
 
 type (
+	// Program holds the declarations of all the modules merged together, the
+	// Modules themselves are kept so that the names can be resolved in the
+	// namespace of the module they were declared in.
 	Program struct {
+		Modules              []*Module
 		Declarations         []*Declaration
 		ExternalDeclarations []*ExternalDeclaration
+		StructDeclarations   []*StructDeclaration
+		EnumDeclarations     []*EnumDeclaration
+		Globals              []*Global
+		Constants            []*Constant
+	}
+
+	// Module is a single source file of the program. Modules are ordered so
+	// that every module comes after all the modules it imports.
+	Module struct {
+		Path                 string
+		Imports              []*Import
+		Declarations         []*Declaration
+		ExternalDeclarations []*ExternalDeclaration
+		StructDeclarations   []*StructDeclaration
+		EnumDeclarations     []*EnumDeclaration
+		Globals              []*Global
+		Constants            []*Constant
+	}
+
+	// Import makes the declarations of the imported module visible in the
+	// importing module, Module is set once the imported file is loaded.
+	Import struct {
+		Path     string
+		Position lexer.Position
+		Module   *Module
 	}
 
 	Declaration struct {
-		Type       BasicType
+		Type       Type
 		Identifier *Identifier
 		Args       []Argument
 		Body       Block
@@ -59,20 +106,125 @@ type (
 		Variadic   bool
 	}
 
+	StructDeclaration struct {
+		Identifier *Identifier
+		Fields     []Field
+	}
+
+	// EnumDeclaration declares a type whose values are one of the Variants,
+	// represented by their integer Number.
+	EnumDeclaration struct {
+		Identifier *Identifier
+		Variants   []EnumVariant
+	}
+
+	// Global is a variable declared at the top level of a module, which lives
+	// for the whole run of the program. Globals of basic types are initialized
+	// by a constant value, which is Folded by the type resolver, the others can
+	// only be zero-initialized.
+	Global struct {
+		Identifier *Identifier
+		Type       Type
+		Value      Value
+		Folded     *Literal
+	}
+
+	// Constant is a named value computed at compile time. The type resolver
+	// folds the value to a literal, which is used in place of the name.
+	Constant struct {
+		Identifier *Identifier
+		Type       Type
+		Value      Value
+		Folded     *Literal
+	}
+
 	Argument struct {
 		Type       Type
 		Identifier *Identifier
 	}
+
+	// Field is a single member of a StructDeclaration, it is visited as a part
+	// of the declaration.
+	Field struct {
+		Type       Type
+		Identifier *Identifier
+	}
+
+	// EnumVariant is a single member of an EnumDeclaration. Its Number is the
+	// constant Value when given, or the number of the previous variant plus
+	// one, starting from zero. Numbers are assigned by the type resolver.
+	EnumVariant struct {
+		Identifier *Identifier
+		Value      Value
+		Number     int64
+	}
 )
 
 func (p *Program) Accept(v Visitor) error             { return v.VisitProgram(p) }
 func (d *Declaration) Accept(v Visitor) error         { return v.VisitDeclaration(d) }
 func (d *ExternalDeclaration) Accept(v Visitor) error { return v.VisitExternalDeclaration(d) }
+func (d *StructDeclaration) Accept(v Visitor) error   { return v.VisitStructDeclaration(d) }
+func (d *EnumDeclaration) Accept(v Visitor) error     { return v.VisitEnumDeclaration(d) }
+func (g *Global) Accept(v Visitor) error              { return v.VisitGlobal(g) }
+func (c *Constant) Accept(v Visitor) error            { return v.VisitConstant(c) }
 func (a *Argument) Accept(v Visitor) error            { return v.VisitArgument(a) }
 
+// Size returns the size of the struct in bytes. Fields are laid out like C
+// does, every field is aligned to its own alignment and the size is padded to
+// the alignment of the struct.
+func (d *StructDeclaration) Size() int {
+	size := 0
+	for _, field := range d.Fields {
+		size = alignUp(size, field.Type.Align()) + field.Type.Size()
+	}
+	return alignUp(size, d.Align())
+}
+
+// Align returns the alignment of the struct, which is the largest alignment
+// of its fields.
+func (d *StructDeclaration) Align() int {
+	align := 1
+	for _, field := range d.Fields {
+		align = max(align, field.Type.Align())
+	}
+	return align
+}
+
+// Field looks up a field by name, returning it together with its offset from
+// the start of the struct. The returned field is nil if it does not exist.
+func (d *StructDeclaration) Field(name string) (*Field, int) {
+	offset := 0
+	for i := range d.Fields {
+		offset = alignUp(offset, d.Fields[i].Type.Align())
+		if d.Fields[i].Identifier.Name == name {
+			return &d.Fields[i], offset
+		}
+		offset += d.Fields[i].Type.Size()
+	}
+	return nil, 0
+}
+
+func alignUp(n, align int) int {
+	if n%align != 0 {
+		n += align - n%align
+	}
+	return n
+}
+
+// Variant looks up a variant by name, returning nil if it does not exist.
+func (d *EnumDeclaration) Variant(name string) *EnumVariant {
+	for i := range d.Variants {
+		if d.Variants[i].Identifier.Name == name {
+			return &d.Variants[i]
+		}
+	}
+	return nil
+}
+
 type Type interface {
 	String() string
 	Size() int
+	Align() int
 	Accept(v Visitor) error
 	Equals(o Type) bool
 }
@@ -87,16 +239,33 @@ const (
 	String
 	Unit
 	Undefined
+	I8
+	I16
+	I32
+	U8
+	U16
+	U32
+	U64
 )
 
+// IntegerTypes are the basic types holding integers. Int is a signed 64-bit
+// integer, the others are named by their signedness and size in bits.
+var IntegerTypes = []BasicType{Int, I8, I16, I32, U8, U16, U32, U64}
+
 func BasicTypePtr(t BasicType) *BasicType {
 	return &t
 }
 
 func (b *BasicType) Size() int {
 	switch *b {
-	case Int, Float, Bool, String:
+	case Int, Float, Bool, String, U64:
 		return 8
+	case I32, U32:
+		return 4
+	case I16, U16:
+		return 2
+	case I8, U8:
+		return 1
 	case Unit, Undefined:
 		fallthrough
 	default:
@@ -104,6 +273,10 @@ func (b *BasicType) Size() int {
 	}
 }
 
+func (b *BasicType) Align() int {
+	return max(b.Size(), 1)
+}
+
 func (b *BasicType) Accept(v Visitor) error {
 	return v.VisitBasicType(b)
 }
@@ -113,15 +286,52 @@ func (b *BasicType) Equals(o Type) bool {
 	return ok && *val == *b
 }
 
+// IsInteger reports whether t is one of the IntegerTypes.
+func IsInteger(t Type) bool {
+	if b, ok := t.(*BasicType); ok {
+		switch *b {
+		case Int, I8, I16, I32, U8, U16, U32, U64:
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnsigned reports whether t is one of the unsigned integer types, which
+// are divided, compared and shifted right as unsigned numbers.
+func IsUnsigned(t Type) bool {
+	if b, ok := t.(*BasicType); ok {
+		switch *b {
+		case U8, U16, U32, U64:
+			return true
+		}
+	}
+	return false
+}
+
+// Identical reports whether two types are exactly the same. Unlike Equals it
+// is symmetric, so an array is not identical to a slice of the same element.
+func Identical(a, b Type) bool {
+	return a.Equals(b) && b.Equals(a)
+}
+
+// ArrayType stores its elements inline, so an array of arrays is laid out
+// row by row. The Length of an array whose length is given by a Constant is
+// set by the type resolver.
 type ArrayType struct {
-	Element BasicType
-	Length  int
+	Element  Type
+	Length   int
+	Constant *Identifier
 }
 
 func (t *ArrayType) Size() int {
 	return t.Element.Size() * t.Length
 }
 
+func (t *ArrayType) Align() int {
+	return t.Element.Align()
+}
+
 func (t *ArrayType) String() string {
 	return fmt.Sprintf("[%d]%s", t.Length, t.Element.String())
 }
@@ -132,18 +342,22 @@ func (t *ArrayType) Accept(v Visitor) error {
 
 func (t *ArrayType) Equals(o Type) bool {
 	if val, ok := o.(*ArrayType); ok {
-		return t.Length == val.Length && t.Element == val.Element
+		return t.Length == val.Length && Identical(t.Element, val.Element)
 	}
 	return false
 }
 
 type SliceType struct {
-	Element          BasicType
+	Element          Type
 	LengthIdentifier *Identifier
 }
 
 func (t *SliceType) Size() int {
-	return 16 // pointer + length
+	return 24 // pointer + length + capacity
+}
+
+func (t *SliceType) Align() int {
+	return 8
 }
 
 func (t *SliceType) String() string {
@@ -159,22 +373,26 @@ func (t *SliceType) Accept(v Visitor) error {
 
 func (t *SliceType) Equals(o Type) bool {
 	if val, ok := o.(*ArrayType); ok {
-		return t.Element == val.Element
+		return Identical(t.Element, val.Element)
 	}
 	if val, ok := o.(*SliceType); ok {
-		return t.Element == val.Element
+		return Identical(t.Element, val.Element)
 	}
 	return false
 }
 
 type PointerType struct {
-	Inner *BasicType
+	Inner Type
 }
 
 func (t *PointerType) Size() int {
 	return 8
 }
 
+func (t *PointerType) Align() int {
+	return 8
+}
+
 func (t *PointerType) String() string {
 	return fmt.Sprintf("^%s", t.Inner.String())
 }
@@ -185,11 +403,123 @@ func (t *PointerType) Accept(v Visitor) error {
 
 func (t *PointerType) Equals(o Type) bool {
 	if pointerType, ok := o.(*PointerType); ok {
-		return t.Inner.Equals(pointerType.Inner)
+		return Identical(t.Inner, pointerType.Inner)
+	}
+	return false
+}
+
+// StructType refers to a StructDeclaration by name. The Declaration is filled
+// in by the type resolver.
+type StructType struct {
+	Identifier  *Identifier
+	Declaration *StructDeclaration
+}
+
+func (t *StructType) Size() int {
+	if t.Declaration == nil {
+		return 0
+	}
+	return t.Declaration.Size()
+}
+
+func (t *StructType) Align() int {
+	if t.Declaration == nil {
+		return 1
+	}
+	return t.Declaration.Align()
+}
+
+func (t *StructType) String() string {
+	return t.Identifier.Name
+}
+
+func (t *StructType) Accept(v Visitor) error {
+	return v.VisitStructType(t)
+}
+
+func (t *StructType) Equals(o Type) bool {
+	if structType, ok := o.(*StructType); ok {
+		return t.Declaration != nil && t.Declaration == structType.Declaration
 	}
 	return false
 }
 
+// EnumType refers to an EnumDeclaration by name. Enum names are parsed as
+// struct types, the name resolver replaces them once the name is known to
+// refer to an enum and the Declaration is filled in by the type resolver.
+type EnumType struct {
+	Identifier  *Identifier
+	Declaration *EnumDeclaration
+}
+
+func (t *EnumType) Size() int {
+	return 8
+}
+
+func (t *EnumType) Align() int {
+	return 8
+}
+
+func (t *EnumType) String() string {
+	return t.Identifier.Name
+}
+
+func (t *EnumType) Accept(v Visitor) error {
+	return v.VisitEnumType(t)
+}
+
+func (t *EnumType) Equals(o Type) bool {
+	if enumType, ok := o.(*EnumType); ok {
+		return t.Declaration != nil && t.Declaration == enumType.Declaration
+	}
+	return false
+}
+
+// FunctionType is the type of a pointer to a function. Functions declared
+// with a variadic argument list have a Variadic type, which can't be written
+// down, so they can only be called directly.
+type FunctionType struct {
+	Parameters []Type
+	Return     Type
+	Variadic   bool
+}
+
+func (t *FunctionType) Size() int {
+	return 8
+}
+
+func (t *FunctionType) Align() int {
+	return 8
+}
+
+func (t *FunctionType) String() string {
+	parameters := []string{}
+	for _, parameter := range t.Parameters {
+		parameters = append(parameters, parameter.String())
+	}
+	if t.Variadic {
+		parameters = append(parameters, "...")
+	}
+	return fmt.Sprintf("fn(%s) %s", strings.Join(parameters, ", "), t.Return.String())
+}
+
+func (t *FunctionType) Accept(v Visitor) error {
+	return v.VisitFunctionType(t)
+}
+
+func (t *FunctionType) Equals(o Type) bool {
+	functionType, ok := o.(*FunctionType)
+	if !ok || t.Variadic != functionType.Variadic || len(t.Parameters) != len(functionType.Parameters) {
+		return false
+	}
+	for i, parameter := range t.Parameters {
+		if !Identical(parameter, functionType.Parameters[i]) {
+			return false
+		}
+	}
+	return Identical(t.Return, functionType.Return)
+}
+
 // operators
 //
 //go:generate stringer -type=BinaryOperator
@@ -211,6 +541,9 @@ const (
 	ShiftRight
 	LogicAnd
 	LogicOr
+	BitAnd
+	BitOr
+	BitXor
 )
 
 var BinaryOperatorTokens = map[string]BinaryOperator{
@@ -229,34 +562,58 @@ var BinaryOperatorTokens = map[string]BinaryOperator{
 	">>": ShiftRight,
 	"&&": LogicAnd,
 	"||": LogicOr,
+	"&":  BitAnd,
+	"|":  BitOr,
+	"^":  BitXor,
 }
 
+// BinaryOperatorPrecedence follows C, so the bitwise operators bind weaker
+// than comparisons: a & mask == 0 means a & (mask == 0).
 var BinaryOperatorPrecedence = map[BinaryOperator]int{
 	LogicOr:  1,
 	LogicAnd: 2,
-	Equality: 3, Inequality: 3,
-	Less: 4, Greater: 4, LessEqual: 4, GreaterEqual: 4,
-	Addition: 5, Subtraction: 5,
-	Multiplication: 6, Division: 6, Modulo: 6,
-	ShiftLeft: 7, ShiftRight: 7,
+	BitOr:    3,
+	BitXor:   4,
+	BitAnd:   5,
+	Equality: 6, Inequality: 6,
+	Less: 7, Greater: 7, LessEqual: 7, GreaterEqual: 7,
+	Addition: 8, Subtraction: 8,
+	Multiplication: 9, Division: 9, Modulo: 9,
+	ShiftLeft: 10, ShiftRight: 10,
 }
 
+// BinaryOperatorApplies maps an operator to the types of the operands it can
+// be applied to. The right shift is arithmetic for the signed integers and
+// logical for the unsigned ones. Strings are equal when their bytes are, and
+// adding them concatenates them into a new heap allocation.
 var BinaryOperatorApplies = map[BinaryOperator]map[BasicType]bool{
-	Addition:       {Int: true, Float: true},
-	Subtraction:    {Int: true, Float: true},
-	Multiplication: {Int: true, Float: true},
-	Division:       {Int: true, Float: true},
-	Modulo:         {Int: true},
-	Equality:       {Int: true, Float: true, Bool: true},
-	Inequality:     {Int: true, Float: true, Bool: true},
-	Less:           {Int: true, Float: true},
-	Greater:        {Int: true, Float: true},
-	LessEqual:      {Int: true, Float: true},
-	GreaterEqual:   {Int: true, Float: true},
-	ShiftLeft:      {Int: true},
-	ShiftRight:     {Int: true},
-	LogicAnd:       {Bool: true, Int: true},
-	LogicOr:        {Bool: true, Int: true},
+	Addition:       integers(Float, String),
+	Subtraction:    integers(Float),
+	Multiplication: integers(Float),
+	Division:       integers(Float),
+	Modulo:         integers(),
+	Equality:       integers(Float, Bool, String),
+	Inequality:     integers(Float, Bool, String),
+	Less:           integers(Float),
+	Greater:        integers(Float),
+	LessEqual:      integers(Float),
+	GreaterEqual:   integers(Float),
+	ShiftLeft:      integers(),
+	ShiftRight:     integers(),
+	LogicAnd:       {Bool: true},
+	LogicOr:        {Bool: true},
+	BitAnd:         integers(),
+	BitOr:          integers(),
+	BitXor:         integers(),
+}
+
+// integers returns a set of the IntegerTypes and the other types.
+func integers(others ...BasicType) map[BasicType]bool {
+	set := map[BasicType]bool{}
+	for _, t := range append(IntegerTypes, others...) {
+		set[t] = true
+	}
+	return set
 }
 
 var BoolOperators = map[BinaryOperator]bool{
@@ -275,18 +632,64 @@ const (
 	Inversion UnaryOperator = iota
 	LogicNegation
 	AddressOf
+	BitNot
 )
 
+// UnaryOperatorTokens shares "^" with BinaryOperatorTokens, the parser tells
+// them apart by position: in front of an operand "^" takes the address, after
+// one it is the xor operator.
 var UnaryOperatorTokens = map[string]UnaryOperator{
 	"-": Inversion,
 	"!": LogicNegation,
 	"^": AddressOf,
+	"~": BitNot,
 }
 
 var UnaryOperatorApplies = map[UnaryOperator]map[BasicType]bool{
-	Inversion:     {Int: true, Float: true},
+	Inversion:     integers(Float),
 	LogicNegation: {Bool: true},
-	AddressOf:     {Bool: true, Int: true, Float: true},
+	BitNot:        integers(),
+}
+
+// ConversionApplies maps the target type of a conversion to the types that
+// can be converted to it. Converting an integer to bool compares it to zero,
+// converting between integers truncates or extends the value. The values of
+// u64 can exceed the range of int, so they are not converted to floats.
+// Numbers converted to strings are formatted into a new heap allocation.
+var ConversionApplies = map[BasicType]map[BasicType]bool{
+	Int:    integers(Float, Bool),
+	I8:     integers(Float, Bool),
+	I16:    integers(Float, Bool),
+	I32:    integers(Float, Bool),
+	U8:     integers(Float, Bool),
+	U16:    integers(Float, Bool),
+	U32:    integers(Float, Bool),
+	U64:    integers(Bool),
+	Float:  {Float: true, Int: true, I8: true, I16: true, I32: true, U8: true, U16: true, U32: true},
+	Bool:   integers(Bool),
+	String: integers(Float),
+}
+
+// IsAllocation reports whether the value results in a new heap allocation,
+// which is the result of make, a concatenation of strings, a number
+// converted to a string or a string converted to bytes.
+func IsAllocation(v Value) bool {
+	switch v := v.(type) {
+	case *Separated:
+		return IsAllocation(v.Value)
+	case *Make:
+		return true
+	case *Binary:
+		return v.Operator == Addition && v.Left.GetType().Equals(BasicTypePtr(String))
+	case *Conversion:
+		if v.Value.GetType().Equals(BasicTypePtr(String)) {
+			_, toSlice := v.Type.(*SliceType)
+			return toSlice
+		}
+		_, fromBasic := v.Value.GetType().(*BasicType)
+		return v.Type.Equals(BasicTypePtr(String)) && fromBasic
+	}
+	return false
 }
 
 // expressions
@@ -316,6 +719,14 @@ type (
 		ExpressionBase
 		Value Value
 	}
+	// Break exits the innermost enclosing loop.
+	Break struct {
+		ExpressionBase
+	}
+	// Continue skips to the next iteration of the innermost enclosing loop.
+	Continue struct {
+		ExpressionBase
+	}
 	Bind struct {
 		ExpressionBase
 		Identifier *Identifier
@@ -330,6 +741,8 @@ type (
 )
 
 func (r *Return) Accept(v Visitor) error     { return v.VisitReturn(r) }
+func (b *Break) Accept(v Visitor) error      { return v.VisitBreak(b) }
+func (c *Continue) Accept(v Visitor) error   { return v.VisitContinue(c) }
 func (b *Bind) Accept(v Visitor) error       { return v.VisitBind(b) }
 func (a *Assignment) Accept(v Visitor) error { return v.VisitAssignment(a) }
 
@@ -372,8 +785,8 @@ type (
 	}
 	Call struct {
 		PrimaryBase
-		Identifier *Identifier
-		Arguments  []Value
+		Function  Primary // an identifier of the function for direct calls
+		Arguments []Value
 	}
 	Separated struct {
 		PrimaryBase
@@ -392,8 +805,16 @@ type (
 	}
 	Index struct {
 		PrimaryBase
-		Identifier *Identifier
-		Index      Value
+		Value Primary
+		Index Value
+	}
+	// Slice takes the elements of an array or slice from Low up to High,
+	// omitted bounds default to the start and the end.
+	Slice struct {
+		PrimaryBase
+		Value Primary
+		Low   Value
+		High  Value
 	}
 	ArrayLiteral struct {
 		PrimaryBase
@@ -401,7 +822,7 @@ type (
 	}
 	Dereference struct {
 		PrimaryBase
-		Value *Identifier
+		Value Primary
 	}
 	Loop struct {
 		PrimaryBase
@@ -410,24 +831,112 @@ type (
 	}
 	Make struct {
 		PrimaryBase
-		Type   BasicType
-		Length Value
+		Type   Type
+		Length Value // nil when a single value is allocated
 	}
 	Release struct {
 		PrimaryBase
-		Value *Identifier
+		Value Value
+	}
+	FieldAccess struct {
+		PrimaryBase
+		Value Primary
+		Field *Identifier
+	}
+	// Conversion converts a value between the numeric and boolean types, or
+	// between a string and a slice of its bytes.
+	Conversion struct {
+		PrimaryBase
+		Type  Type
+		Value Value
+	}
+	// Length is the number of elements of an array or slice.
+	Length struct {
+		PrimaryBase
+		Value Value
+	}
+	// Append is the slice with Value added after its last element. The
+	// elements are reallocated when the slice is out of capacity.
+	Append struct {
+		PrimaryBase
+		Slice Value
+		Value Value
+	}
+	// Copy copies as many elements as fit from Source to Destination and
+	// results in their count.
+	Copy struct {
+		PrimaryBase
+		Destination Value
+		Source      Value
+	}
+	// VariantAccess is the value of a Variant of the Enum, the variant name
+	// is resolved by the type resolver once the enum is known.
+	VariantAccess struct {
+		PrimaryBase
+		Enum    *Identifier
+		Variant *Identifier
+	}
+	// Match compares the value to the patterns of the arms in order and
+	// results in the body of the first arm with an equal pattern, or in Else
+	// when none is equal.
+	Match struct {
+		PrimaryBase
+		Value Value
+		Arms  []MatchArm
+		Else  Value
 	}
 )
 
-func (l *Literal) Accept(v Visitor) error      { return v.VisitLiteral(l) }
-func (i *Identifier) Accept(v Visitor) error   { return v.VisitIdentifier(i) }
-func (c *Call) Accept(v Visitor) error         { return v.VisitCall(c) }
-func (s *Separated) Accept(v Visitor) error    { return v.VisitSeparated(s) }
-func (b *Block) Accept(v Visitor) error        { return v.VisitBlock(b) }
-func (c *Condition) Accept(v Visitor) error    { return v.VisitCondition(c) }
-func (c *Index) Accept(v Visitor) error        { return v.VisitIndex(c) }
-func (a *ArrayLiteral) Accept(v Visitor) error { return v.VisitArrayLiteral(a) }
-func (d *Dereference) Accept(v Visitor) error  { return v.VisitDereference(d) }
-func (l *Loop) Accept(v Visitor) error         { return v.VisitLoop(l) }
-func (m *Make) Accept(v Visitor) error         { return v.VisitMake(m) }
-func (r *Release) Accept(v Visitor) error      { return v.VisitRelease(r) }
+// MatchArm is a single arm of a Match, it is visited as a part of the match.
+// The patterns are constant values, which the type resolver folds to Cases.
+type MatchArm struct {
+	Patterns []Value
+	Cases    []int64
+	Body     Value
+}
+
+func (l *Literal) Accept(v Visitor) error       { return v.VisitLiteral(l) }
+func (i *Identifier) Accept(v Visitor) error    { return v.VisitIdentifier(i) }
+func (c *Call) Accept(v Visitor) error          { return v.VisitCall(c) }
+func (s *Separated) Accept(v Visitor) error     { return v.VisitSeparated(s) }
+func (b *Block) Accept(v Visitor) error         { return v.VisitBlock(b) }
+func (c *Condition) Accept(v Visitor) error     { return v.VisitCondition(c) }
+func (c *Index) Accept(v Visitor) error         { return v.VisitIndex(c) }
+func (s *Slice) Accept(v Visitor) error         { return v.VisitSlice(s) }
+func (a *ArrayLiteral) Accept(v Visitor) error  { return v.VisitArrayLiteral(a) }
+func (d *Dereference) Accept(v Visitor) error   { return v.VisitDereference(d) }
+func (l *Loop) Accept(v Visitor) error          { return v.VisitLoop(l) }
+func (m *Make) Accept(v Visitor) error          { return v.VisitMake(m) }
+func (r *Release) Accept(v Visitor) error       { return v.VisitRelease(r) }
+func (f *FieldAccess) Accept(v Visitor) error   { return v.VisitFieldAccess(f) }
+func (c *Conversion) Accept(v Visitor) error    { return v.VisitConversion(c) }
+func (l *Length) Accept(v Visitor) error        { return v.VisitLength(l) }
+func (a *Append) Accept(v Visitor) error        { return v.VisitAppend(a) }
+func (c *Copy) Accept(v Visitor) error          { return v.VisitCopy(c) }
+func (a *VariantAccess) Accept(v Visitor) error { return v.VisitVariantAccess(a) }
+func (m *Match) Accept(v Visitor) error         { return v.VisitMatch(m) }
+
+// Exhaustive reports whether every value of the matched type is matched by
+// some arm, which is the case when there is an else arm or all the variants
+// of an enum are matched.
+func (m *Match) Exhaustive() bool {
+	if m.Else != nil {
+		return true
+	}
+	enumType, ok := m.Value.GetType().(*EnumType)
+	if !ok || enumType.Declaration == nil {
+		return false
+	}
+	matched := map[int64]bool{}
+	for _, arm := range m.Arms {
+		for _, c := range arm.Cases {
+			matched[c] = true
+		}
+	}
+	for _, variant := range enumType.Declaration.Variants {
+		if !matched[variant.Number] {
+			return false
+		}
+	}
+	return true
+}
@@ -14,11 +14,18 @@ func _() {
 	_ = x[String-3]
 	_ = x[Unit-4]
 	_ = x[Undefined-5]
+	_ = x[I8-6]
+	_ = x[I16-7]
+	_ = x[I32-8]
+	_ = x[U8-9]
+	_ = x[U16-10]
+	_ = x[U32-11]
+	_ = x[U64-12]
 }
 
-const _BasicType_name = "IntFloatBoolStringUnitUndefined"
+const _BasicType_name = "IntFloatBoolStringUnitUndefinedI8I16I32U8U16U32U64"
 
-var _BasicType_index = [...]uint8{0, 3, 8, 12, 18, 22, 31}
+var _BasicType_index = [...]uint8{0, 3, 8, 12, 18, 22, 31, 33, 36, 39, 41, 44, 47, 50}
 
 func (i BasicType) String() string {
 	if i < 0 || i >= BasicType(len(_BasicType_index)-1) {
@@ -23,11 +23,14 @@ func _() {
 	_ = x[ShiftRight-12]
 	_ = x[LogicAnd-13]
 	_ = x[LogicOr-14]
+	_ = x[BitAnd-15]
+	_ = x[BitOr-16]
+	_ = x[BitXor-17]
 }
 
-const _BinaryOperator_name = "AdditionSubtractionMultiplicationDivisionModuloEqualityInequalityLessGreaterLessEqualGreaterEqualShiftLeftShiftRightLogicAndLogicOr"
+const _BinaryOperator_name = "AdditionSubtractionMultiplicationDivisionModuloEqualityInequalityLessGreaterLessEqualGreaterEqualShiftLeftShiftRightLogicAndLogicOrBitAndBitOrBitXor"
 
-var _BinaryOperator_index = [...]uint8{0, 8, 19, 33, 41, 47, 55, 65, 69, 76, 85, 97, 106, 116, 124, 131}
+var _BinaryOperator_index = [...]uint8{0, 8, 19, 33, 41, 47, 55, 65, 69, 76, 85, 97, 106, 116, 124, 131, 137, 142, 148}
 
 func (i BinaryOperator) String() string {
 	if i < 0 || i >= BinaryOperator(len(_BinaryOperator_index)-1) {
@@ -11,11 +11,12 @@ func _() {
 	_ = x[Inversion-0]
 	_ = x[LogicNegation-1]
 	_ = x[AddressOf-2]
+	_ = x[BitNot-3]
 }
 
-const _UnaryOperator_name = "InversionLogicNegationAddressOf"
+const _UnaryOperator_name = "InversionLogicNegationAddressOfBitNot"
 
-var _UnaryOperator_index = [...]uint8{0, 9, 22, 31}
+var _UnaryOperator_index = [...]uint8{0, 9, 22, 31, 37}
 
 func (i UnaryOperator) String() string {
 	if i < 0 || i >= UnaryOperator(len(_UnaryOperator_index)-1) {
@@ -20,8 +20,10 @@ const (
 	light_blue color = "#8bd6b1"
 )
 
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
 func escape(val string) string {
-	return strings.ReplaceAll(val, "\"", "\\\"")
+	return escaper.Replace(val)
 }
 
 type AstVisualizer struct {
@@ -86,11 +88,39 @@ func (v *AstVisualizer) VisitProgram(p *ast.Program) error {
 	v.WriteNode("Program", none)
 	defer v.Pop()
 
+	for _, module := range p.Modules {
+		v.WriteNode("Module: %s", none, module.Path)
+		for _, imp := range module.Imports {
+			v.WriteNode("Import: %s", none, imp.Path)
+			v.Pop()
+		}
+		v.Pop()
+	}
+	for _, decl := range p.StructDeclarations {
+		if err := decl.Accept(v); err != nil {
+			return err
+		}
+	}
+	for _, decl := range p.EnumDeclarations {
+		if err := decl.Accept(v); err != nil {
+			return err
+		}
+	}
 	for _, extrn := range p.ExternalDeclarations {
 		if err := extrn.Accept(v); err != nil {
 			return err
 		}
 	}
+	for _, constant := range p.Constants {
+		if err := constant.Accept(v); err != nil {
+			return err
+		}
+	}
+	for _, global := range p.Globals {
+		if err := global.Accept(v); err != nil {
+			return err
+		}
+	}
 	for _, decl := range p.Declarations {
 		if err := decl.Accept(v); err != nil {
 			return err
@@ -147,6 +177,84 @@ func (v *AstVisualizer) VisitExternalDeclaration(d *ast.ExternalDeclaration) err
 	return nil
 }
 
+func (v *AstVisualizer) VisitStructDeclaration(d *ast.StructDeclaration) error {
+	v.WriteNode("Struct Declaration", none)
+	defer v.Pop()
+	if err := d.Identifier.Accept(v); err != nil {
+		return err
+	}
+
+	v.WriteNode("Fields", none)
+	for _, field := range d.Fields {
+		v.WriteNode("Field", none)
+		if err := field.Type.Accept(v); err != nil {
+			return err
+		}
+		if err := field.Identifier.Accept(v); err != nil {
+			return err
+		}
+		v.Pop()
+	}
+	v.Pop()
+
+	return nil
+}
+
+func (v *AstVisualizer) VisitEnumDeclaration(d *ast.EnumDeclaration) error {
+	v.WriteNode("Enum Declaration", none)
+	defer v.Pop()
+	if err := d.Identifier.Accept(v); err != nil {
+		return err
+	}
+
+	v.WriteNode("Variants", none)
+	for _, variant := range d.Variants {
+		v.WriteNode("Variant: %d", none, variant.Number)
+		if err := variant.Identifier.Accept(v); err != nil {
+			return err
+		}
+		if variant.Value != nil {
+			if err := variant.Value.Accept(v); err != nil {
+				return err
+			}
+		}
+		v.Pop()
+	}
+	v.Pop()
+
+	return nil
+}
+
+func (v *AstVisualizer) VisitGlobal(g *ast.Global) error {
+	v.WriteNode("Global", none)
+	defer v.Pop()
+	defer v.Pop()
+
+	if err := g.Identifier.Accept(v); err != nil {
+		return err
+	}
+	if err := g.Type.Accept(v); err != nil {
+		return err
+	}
+	v.WriteNode("Value", none)
+	return g.Value.Accept(v)
+}
+
+func (v *AstVisualizer) VisitConstant(c *ast.Constant) error {
+	v.WriteNode("Constant", none)
+	defer v.Pop()
+	defer v.Pop()
+
+	if err := c.Identifier.Accept(v); err != nil {
+		return err
+	}
+	if err := c.Type.Accept(v); err != nil {
+		return err
+	}
+	v.WriteNode("Value", none)
+	return c.Value.Accept(v)
+}
+
 func (v *AstVisualizer) VisitArgument(a *ast.Argument) error {
 	v.WriteNode("Argument", none)
 	defer v.Pop()
@@ -184,10 +292,9 @@ func (v *AstVisualizer) VisitBasicType(t *ast.BasicType) error {
 }
 
 func (v *AstVisualizer) VisitArrayType(t *ast.ArrayType) error {
-	v.WriteNode("ArrayType", orange)
+	v.WriteNode("ArrayType: %d", orange, t.Length)
 	defer v.Pop()
-	v.writeBasicType(t.Element)
-	return nil
+	return t.Element.Accept(v)
 }
 
 func (v *AstVisualizer) VisitSliceType(t *ast.SliceType) error {
@@ -198,8 +305,7 @@ func (v *AstVisualizer) VisitSliceType(t *ast.SliceType) error {
 			return err
 		}
 	}
-	v.writeBasicType(t.Element)
-	return nil
+	return t.Element.Accept(v)
 }
 
 func (v *AstVisualizer) VisitPointerType(t *ast.PointerType) error {
@@ -208,12 +314,46 @@ func (v *AstVisualizer) VisitPointerType(t *ast.PointerType) error {
 	return t.Inner.Accept(v)
 }
 
+func (v *AstVisualizer) VisitFunctionType(t *ast.FunctionType) error {
+	v.WriteNode("FunctionType", none)
+	defer v.Pop()
+	var err error
+	for _, parameter := range t.Parameters {
+		err = errors.Join(err, parameter.Accept(v))
+	}
+	return errors.Join(err, t.Return.Accept(v))
+}
+
+func (v *AstVisualizer) VisitStructType(t *ast.StructType) error {
+	v.WriteNode("StructType: %s", orange, t.Identifier.Name)
+	v.Pop()
+	return nil
+}
+
+func (v *AstVisualizer) VisitEnumType(t *ast.EnumType) error {
+	v.WriteNode("EnumType: %s", orange, t.Identifier.Name)
+	v.Pop()
+	return nil
+}
+
 func (v *AstVisualizer) VisitReturn(r *ast.Return) error {
 	v.WriteNode("Return", none)
 	defer v.Pop()
 	return r.Value.Accept(v)
 }
 
+func (v *AstVisualizer) VisitBreak(b *ast.Break) error {
+	v.WriteNode("Break", none)
+	v.Pop()
+	return nil
+}
+
+func (v *AstVisualizer) VisitContinue(c *ast.Continue) error {
+	v.WriteNode("Continue", none)
+	v.Pop()
+	return nil
+}
+
 func (v *AstVisualizer) VisitBind(b *ast.Bind) error {
 	v.WriteNode("Bind", none)
 	defer v.Pop()
@@ -249,7 +389,7 @@ func (v *AstVisualizer) VisitCall(c *ast.Call) error {
 	v.WriteNode("Call", none)
 	defer v.Pop()
 
-	if err := c.Identifier.Accept(v); err != nil {
+	if err := c.Function.Accept(v); err != nil {
 		return err
 	}
 	v.WriteNode("Arguments", none)
@@ -333,6 +473,41 @@ func (v *AstVisualizer) VisitCondition(c *ast.Condition) error {
 	return nil
 }
 
+func (v *AstVisualizer) VisitMatch(m *ast.Match) error {
+	v.WriteNode("Match", none)
+	defer v.Pop()
+
+	v.WriteNode("Value", none)
+	if err := m.Value.Accept(v); err != nil {
+		return err
+	}
+	v.Pop()
+
+	for _, arm := range m.Arms {
+		v.WriteNode("Arm", none)
+		v.WriteNode("Patterns", none)
+		for _, pattern := range arm.Patterns {
+			if err := pattern.Accept(v); err != nil {
+				return err
+			}
+		}
+		v.Pop()
+		if err := arm.Body.Accept(v); err != nil {
+			return err
+		}
+		v.Pop()
+	}
+
+	if m.Else != nil {
+		v.WriteNode("Else", none)
+		if err := m.Else.Accept(v); err != nil {
+			return err
+		}
+		v.Pop()
+	}
+	return nil
+}
+
 func (v *AstVisualizer) VisitAssignment(a *ast.Assignment) error {
 	v.WriteNode("Assignment", none)
 	defer v.Pop()
@@ -372,13 +547,46 @@ func (v *AstVisualizer) VisitMake(m *ast.Make) error {
 	err = errors.Join(err, m.Type.Accept(v))
 	v.Pop()
 
-	v.WriteNode("Length", none)
-	err = errors.Join(err, m.Length.Accept(v))
-	v.Pop()
+	if m.Length != nil {
+		v.WriteNode("Length", none)
+		err = errors.Join(err, m.Length.Accept(v))
+		v.Pop()
+	}
 
 	return err
 }
 
+func (v *AstVisualizer) VisitConversion(c *ast.Conversion) error {
+	v.WriteNode("Conversion", none)
+	defer v.Pop()
+
+	err := c.GetType().Accept(v)
+	return errors.Join(err, c.Value.Accept(v))
+}
+
+func (v *AstVisualizer) VisitLength(l *ast.Length) error {
+	v.WriteNode("Length", none)
+	defer v.Pop()
+
+	return l.Value.Accept(v)
+}
+
+func (v *AstVisualizer) VisitAppend(a *ast.Append) error {
+	v.WriteNode("Append", none)
+	defer v.Pop()
+
+	err := a.Slice.Accept(v)
+	return errors.Join(err, a.Value.Accept(v))
+}
+
+func (v *AstVisualizer) VisitCopy(c *ast.Copy) error {
+	v.WriteNode("Copy", none)
+	defer v.Pop()
+
+	err := c.Destination.Accept(v)
+	return errors.Join(err, c.Source.Accept(v))
+}
+
 func (v *AstVisualizer) VisitRelease(r *ast.Release) error {
 	v.WriteNode("Release", none)
 	defer v.Pop()
@@ -402,10 +610,52 @@ func (v *AstVisualizer) VisitArrayLiteral(a *ast.ArrayLiteral) error {
 func (v *AstVisualizer) VisitIndex(i *ast.Index) error {
 	v.WriteNode("Index", none)
 	defer v.Pop()
-	if err := i.Identifier.Accept(v); err != nil {
+	if err := i.Value.Accept(v); err != nil {
 		return err
 	}
 	v.WriteNode("Index value", none)
 	defer v.Pop()
 	return i.Index.Accept(v)
 }
+
+func (v *AstVisualizer) VisitSlice(s *ast.Slice) error {
+	v.WriteNode("Slice", none)
+	defer v.Pop()
+	if err := s.Value.Accept(v); err != nil {
+		return err
+	}
+
+	if s.Low != nil {
+		v.WriteNode("Low", none)
+		if err := s.Low.Accept(v); err != nil {
+			return err
+		}
+		v.Pop()
+	}
+
+	if s.High != nil {
+		v.WriteNode("High", none)
+		if err := s.High.Accept(v); err != nil {
+			return err
+		}
+		v.Pop()
+	}
+	return nil
+}
+
+func (v *AstVisualizer) VisitFieldAccess(f *ast.FieldAccess) error {
+	v.WriteNode("FieldAccess", none)
+	defer v.Pop()
+	if err := f.Value.Accept(v); err != nil {
+		return err
+	}
+	v.WriteNode("Field: %s", none, f.Field.Name)
+	defer v.Pop()
+	return f.GetType().Accept(v)
+}
+
+func (v *AstVisualizer) VisitVariantAccess(a *ast.VariantAccess) error {
+	v.WriteNode("VariantAccess: %s::%s", none, a.Enum.Name, a.Variant.Name)
+	defer v.Pop()
+	return a.GetType().Accept(v)
+}
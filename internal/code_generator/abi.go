@@ -0,0 +1,80 @@
+package code_generator
+
+import (
+	"github.com/MisustinIvan/ilang/internal/ast"
+)
+
+// eightbyteClasses classifies every eightbyte of a value of type t according
+// to the System V AMD64 ABI. An eightbyte is of the SSE class (true) if all of
+// the scalars it holds are floats, otherwise it is of the INTEGER class
+// (false). Smaller scalars share an eightbyte with their neighbours.
+func eightbyteClasses(t ast.Type) []bool {
+	classes := make([]bool, (t.Size()+7)/8)
+	for i := range classes {
+		classes[i] = true
+	}
+	forEachScalar(t, 0, func(offset int, scalar ast.Type) {
+		if !scalar.Equals(ast.BasicTypePtr(ast.Float)) {
+			classes[offset/8] = false
+		}
+	})
+	return classes
+}
+
+// forEachScalar calls f with every scalar a value of type t at offset is made
+// of and the offset of the scalar.
+func forEachScalar(t ast.Type, offset int, f func(offset int, scalar ast.Type)) {
+	switch t := t.(type) {
+	case *ast.StructType:
+		for _, field := range t.Declaration.Fields {
+			_, fieldOffset := t.Declaration.Field(field.Identifier.Name)
+			forEachScalar(field.Type, offset+fieldOffset, f)
+		}
+	case *ast.ArrayType:
+		for i := range t.Length {
+			forEachScalar(t.Element, offset+i*t.Element.Size(), f)
+		}
+	case *ast.SliceType:
+		for i := range 3 { // (pointer, length, capacity)
+			f(offset+i*8, ast.BasicTypePtr(ast.Int))
+		}
+	default:
+		f(offset, t)
+	}
+}
+
+// countClasses returns the number of INTEGER and SSE eightbytes in classes.
+func countClasses(classes []bool) (ints, floats int) {
+	for _, isFloat := range classes {
+		if isFloat {
+			floats++
+		} else {
+			ints++
+		}
+	}
+	return ints, floats
+}
+
+// passedInMemory reports whether a value of type t is of the MEMORY class,
+// meaning it is passed on the stack and returned through a hidden pointer
+// supplied by the caller in %rdi.
+func passedInMemory(t ast.Type) bool {
+	structType, ok := t.(*ast.StructType)
+	return ok && structType.Size() > 16
+}
+
+// returnedThroughPointer reports whether a value of type t is returned
+// through a hidden pointer to caller allocated space. Besides MEMORY class
+// structs this applies to arrays, which would otherwise point into the stack
+// frame of the returning function.
+func returnedThroughPointer(t ast.Type) bool {
+	_, isArray := t.(*ast.ArrayType)
+	return isArray || passedInMemory(t)
+}
+
+// returnIntRegs and returnFloatRegs hold the registers used to return the
+// INTEGER and SSE eightbytes of a struct.
+var (
+	returnIntRegs   = []string{"%rax", "%rdx"}
+	returnFloatRegs = []string{"%xmm0", "%xmm1"}
+)
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/MisustinIvan/ilang/internal/ast"
@@ -21,15 +22,36 @@ type functionContext struct {
 	floatArgsGenerated  int              // how many float function arguments had their code generated
 	stackSlotsGenerated int              // how many stack slots had their local-move code generated
 	stackDepth          int              // amount of bytes pushed below current stack frame
+	savedRbx            int              // stack offset of the caller's %rbx, 0 if it isn't preserved
+	loops               []loopContext    // enclosing loops, the innermost one is last
+}
+
+// loopContext holds the labels break and continue jump to and the stack depth
+// at the start of the loop body, which they have to restore before jumping.
+type loopContext struct {
+	continueLabel string
+	breakLabel    string
+	stackDepth    int
+	valueType     ast.Type
 }
 
 type Generator struct {
+	// BoundsChecks enables runtime checking of every index against the
+	// length of the indexed array or slice. Slice bounds are always checked.
+	BoundsChecks bool
+
 	source     strings.Builder
 	prog       *ast.Program
 	ctx        *functionContext
 	externals  map[*ast.Identifier]bool
+	symbols    map[*ast.Identifier]string
+	globals    map[*ast.Identifier]string       // labels of the global variables
+	folded     map[*ast.Identifier]*ast.Literal // values of the named constants
 	constants  map[string]*ast.Literal
 	labelCount int
+
+	stringRuntime bool // whether strings are built at runtime
+	slicing       bool // whether any slice bounds are checked
 }
 
 func (g *Generator) label() string {
@@ -52,9 +74,83 @@ func (g *Generator) loadScalar(offset int) {
 	g.writefln("mov -%d(%%rbp), %%rax", offset)
 }
 
-// storeScalar writes %rax to the local at offset.
-func (g *Generator) storeScalar(offset int) {
-	g.writefln("mov %%rax, -%d(%%rbp)", offset)
+// local returns the memory operand of the local at offset.
+func local(offset int) string {
+	return fmt.Sprintf("-%d(%%rbp)", offset)
+}
+
+// loadSized moves a scalar of type t from the memory operand src into %rax.
+// Integers smaller than eight bytes are sign or zero extended according to
+// their type, so registers always hold the whole 64-bit value.
+func (g *Generator) loadSized(t ast.Type, src string) {
+	unsigned := ast.IsUnsigned(t)
+	switch t.Size() {
+	case 1:
+		if unsigned {
+			g.writefln("movzbq %s, %%rax", src)
+		} else {
+			g.writefln("movsbq %s, %%rax", src)
+		}
+	case 2:
+		if unsigned {
+			g.writefln("movzwq %s, %%rax", src)
+		} else {
+			g.writefln("movswq %s, %%rax", src)
+		}
+	case 4:
+		if unsigned {
+			g.writefln("mov %s, %%eax", src) // zeroes the upper half
+		} else {
+			g.writefln("movslq %s, %%rax", src)
+		}
+	default:
+		g.writefln("mov %s, %%rax", src)
+	}
+}
+
+// storeSized writes the low bytes of %rax holding a scalar of type t to the
+// memory operand dst.
+func (g *Generator) storeSized(t ast.Type, dst string) {
+	switch t.Size() {
+	case 1:
+		g.writefln("mov %%al, %s", dst)
+	case 2:
+		g.writefln("mov %%ax, %s", dst)
+	case 4:
+		g.writefln("mov %%eax, %s", dst)
+	default:
+		g.writefln("mov %%rax, %s", dst)
+	}
+}
+
+// normalize extends the low bytes of %rax holding an integer of type t back
+// to the whole register, after an operation may have carried into the upper
+// bytes.
+func (g *Generator) normalize(t ast.Type) {
+	if !ast.IsInteger(t) {
+		return
+	}
+	unsigned := ast.IsUnsigned(t)
+	switch t.Size() {
+	case 1:
+		if unsigned {
+			g.writeln("movzbq %al, %rax")
+		} else {
+			g.writeln("movsbq %al, %rax")
+		}
+	case 2:
+		if unsigned {
+			g.writeln("movzwq %ax, %rax")
+		} else {
+			g.writeln("movswq %ax, %rax")
+		}
+	case 4:
+		if unsigned {
+			g.writeln("mov %eax, %eax")
+		} else {
+			g.writeln("movslq %eax, %rax")
+		}
+	}
 }
 
 // loadFloatScalar loads the float scalar from offset to %xmm0
@@ -72,16 +168,42 @@ func (g *Generator) loadArrayAddr(offset int) {
 	g.writefln("lea -%d(%%rbp), %%rax", offset)
 }
 
-// loadSlice loads pointer to %rax and length to %rbx from the slice slot at offset.
+// loadSlice loads pointer to %rax, length to %rbx and capacity to %rdx from
+// the slice slot at offset.
 func (g *Generator) loadSlice(offset int) {
-	g.writefln("mov -%d(%%rbp), %%rax", offset)   // pointer
-	g.writefln("mov -%d(%%rbp), %%rbx", offset-8) // length
+	g.writefln("mov -%d(%%rbp), %%rax", offset)    // pointer
+	g.writefln("mov -%d(%%rbp), %%rbx", offset-8)  // length
+	g.writefln("mov -%d(%%rbp), %%rdx", offset-16) // capacity
 }
 
-// storeSlice writes pointer in %rax and length in %rbx into the slice slot at offset.
+// storeSlice writes pointer in %rax, length in %rbx and capacity in %rdx into
+// the slice slot at offset.
 func (g *Generator) storeSlice(offset int) {
-	g.writefln("mov %%rax, -%d(%%rbp)", offset)   // pointer
-	g.writefln("mov %%rbx, -%d(%%rbp)", offset-8) // length
+	g.writefln("mov %%rax, -%d(%%rbp)", offset)    // pointer
+	g.writefln("mov %%rbx, -%d(%%rbp)", offset-8)  // length
+	g.writefln("mov %%rdx, -%d(%%rbp)", offset-16) // capacity
+}
+
+// sliceOf turns a value of type t, which is used as a slice, into a slice
+// value. Arrays don't own their elements, so their capacity is 0.
+func (g *Generator) sliceOf(t ast.Type) {
+	if _, isArray := t.(*ast.ArrayType); isArray {
+		g.writeln("mov $0, %rdx") // capacity
+	}
+}
+
+// callAligned calls a C library function with the stack aligned to 16 bytes.
+func (g *Generator) callAligned(function string) {
+	misalignment := g.ctx.stackDepth % 16
+	if misalignment != 0 {
+		g.adjustStack(16 - misalignment)
+	}
+
+	g.writefln("call %s@PLT", function)
+
+	if misalignment != 0 {
+		g.adjustStack(-(16 - misalignment))
+	}
 }
 
 func (g *Generator) pushIntReg(reg string) {
@@ -109,6 +231,10 @@ func (g *Generator) popFloatReg(reg string) {
 func (g *Generator) pushValue(t ast.Type) {
 	if t.Equals(ast.BasicTypePtr(ast.Float)) {
 		g.pushFloatReg("%xmm0")
+	} else if _, isSlice := t.(*ast.SliceType); isSlice {
+		g.pushIntReg("%rdx")
+		g.pushIntReg("%rbx")
+		g.pushIntReg("%rax")
 	} else {
 		g.pushIntReg("%rax")
 	}
@@ -117,11 +243,68 @@ func (g *Generator) pushValue(t ast.Type) {
 func (g *Generator) popValue(t ast.Type) {
 	if t.Equals(ast.BasicTypePtr(ast.Float)) {
 		g.popFloatReg("%xmm0")
+	} else if _, isSlice := t.(*ast.SliceType); isSlice {
+		g.popIntReg("%rax")
+		g.popIntReg("%rbx")
+		g.popIntReg("%rdx")
 	} else {
 		g.popIntReg("%rax")
 	}
 }
 
+// pushStruct pushes the struct at the address in %rax onto the stack one
+// eightbyte at a time, so that the first eightbyte ends up on top.
+func (g *Generator) pushStruct(t *ast.StructType) {
+	for i := (t.Size()+7)/8 - 1; i >= 0; i-- {
+		g.ctx.stackDepth += 8
+		g.writefln("push %d(%%rax)", i*8)
+	}
+}
+
+// loadIndirect loads a value of type t from the address in %rax according to
+// the value protocol. Arrays and structs are represented by their address, so
+// they are left in place.
+func (g *Generator) loadIndirect(t ast.Type) {
+	switch t := t.(type) {
+	case *ast.ArrayType:
+		g.writefln("mov $%d, %%rbx", t.Length)
+	case *ast.SliceType:
+		g.writeln("mov 16(%rax), %rdx") // capacity
+		g.writeln("mov 8(%rax), %rbx")  // length
+		g.writeln("mov (%rax), %rax")   // pointer
+	case *ast.StructType:
+	default:
+		if t.Equals(ast.BasicTypePtr(ast.Float)) {
+			g.writeln("movsd (%rax), %xmm0")
+		} else {
+			g.loadSized(t, "(%rax)")
+		}
+	}
+}
+
+// storeIndirect stores a value of type t held according to the value protocol
+// to the address in %rcx. Arrays and structs are copied from their address in
+// %rax.
+func (g *Generator) storeIndirect(t ast.Type) {
+	switch t := t.(type) {
+	case *ast.ArrayType, *ast.StructType:
+		g.writeln("mov %rax, %rsi")
+		g.writeln("mov %rcx, %rdi")
+		g.writefln("mov $%d, %%rcx", t.Size())
+		g.writeln("rep movsb")
+	case *ast.SliceType:
+		g.writeln("mov %rax, (%rcx)")   // pointer
+		g.writeln("mov %rbx, 8(%rcx)")  // length
+		g.writeln("mov %rdx, 16(%rcx)") // capacity
+	default:
+		if t.Equals(ast.BasicTypePtr(ast.Float)) {
+			g.writeln("movsd %xmm0, (%rcx)")
+		} else {
+			g.storeSized(t, "(%rcx)")
+		}
+	}
+}
+
 // zeroArray zeroes qwords*8 bytes starting at -offset(%rbp) using rep stosq.
 func (g *Generator) zeroArray(offset, qwords int) {
 	g.writeln("xor %rax, %rax")
@@ -138,6 +321,24 @@ func (g *Generator) copyArray(offset, qwords int) {
 	g.writeln("rep movsq")
 }
 
+// asmString quotes s for the assembler. String literals are decoded by the
+// lexer, so every byte that isn't printable ASCII is written as an octal
+// escape, along with quotes and backslashes.
+func asmString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := range len(s) {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '"' || c == '\\' {
+			fmt.Fprintf(&b, "\\%03o", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
 func (g *Generator) writeln(s string)               { g.source.WriteString(s + "\n") }
 func (g *Generator) writefln(f string, args ...any) { g.writeln(fmt.Sprintf(f, args...)) }
 
@@ -145,38 +346,110 @@ func New(prog *ast.Program) *Generator {
 	return &Generator{
 		prog:      prog,
 		externals: map[*ast.Identifier]bool{},
+		symbols:   map[*ast.Identifier]string{},
+		globals:   map[*ast.Identifier]string{},
+		folded:    map[*ast.Identifier]*ast.Literal{},
 		constants: map[string]*ast.Literal{},
 	}
 }
 
+// assignSymbols picks the assembly symbol of every function. Modules have
+// separate namespaces, so functions of different modules may share a name. The
+// functions of the root module and the external functions keep their names, the
+// clashing functions of the imported modules get the index of their module
+// appended. Globals are local to the object file and get a label numbered
+// in the order of their declaration.
+func (g *Generator) assignSymbols(p *ast.Program) {
+	for i, global := range p.Globals {
+		g.globals[global.Identifier] = fmt.Sprintf(".global_%d_%s", i, global.Identifier.Name)
+	}
+	for _, constant := range p.Constants {
+		g.folded[constant.Identifier] = constant.Folded
+	}
+
+	taken := map[string]bool{}
+	for _, decl := range p.ExternalDeclarations {
+		taken[decl.Identifier.Name] = true
+	}
+
+	root := p.Modules[len(p.Modules)-1]
+	for _, decl := range root.Declarations {
+		g.symbols[decl.Identifier] = decl.Identifier.Name
+		taken[decl.Identifier.Name] = true
+	}
+
+	for i, module := range p.Modules[:len(p.Modules)-1] {
+		for _, decl := range module.Declarations {
+			symbol := decl.Identifier.Name
+			if taken[symbol] {
+				symbol = fmt.Sprintf("%s.%d", symbol, i)
+			}
+			g.symbols[decl.Identifier] = symbol
+			taken[symbol] = true
+		}
+	}
+}
+
 // newContext creates a function context with pre-computed stack offsets.
 func (g *Generator) newContext(d *ast.Declaration) int {
 	locals, stackOffset := findLocals(d)
+	savedRbx := 0
+	if preservesRbx(d.Type) {
+		stackOffset += 8
+		savedRbx = stackOffset
+	}
 	if stackOffset%16 != 0 {
 		stackOffset += 16 - (stackOffset % 16)
 	}
 	g.ctx = &functionContext{
 		currentDecl: d,
 		locals:      locals,
+		savedRbx:    savedRbx,
 	}
 	return stackOffset
 }
 
+// preservesRbx reports whether a function returning a value of type t has to
+// preserve %rbx, which is callee-saved in the System V ABI, so that C code
+// can call back into it through a function pointer. Functions returning
+// arrays and slices return their length in %rbx instead.
+func preservesRbx(t ast.Type) bool {
+	switch t.(type) {
+	case *ast.ArrayType, *ast.SliceType:
+		return false
+	}
+	return true
+}
+
 func (g *Generator) Generate() (string, error) {
 	err := g.prog.Accept(g)
+	if g.BoundsChecks {
+		g.generateBoundsCheckFailure()
+	}
+	if g.slicing {
+		g.generateSliceBoundsFailure()
+	}
+	if g.stringRuntime {
+		g.generateStringRuntime()
+	}
 	g.writeln("")
 	g.writeln("# data section")
 	g.writeln(".data")
 	g.writeln(".const_neg_one:")
 	g.writeln(".double -1.0")
+	for _, global := range g.prog.Globals {
+		err = errors.Join(err, global.Accept(g))
+	}
+	g.writeln(".data")
 	for id, l := range g.constants {
 		switch {
 		case l.GetType().Equals(ast.BasicTypePtr(ast.String)):
 			g.writeln(id + ":")
-			g.writeln(".asciz " + l.Value)
+			g.writeln(".asciz " + asmString(l.Value[1:len(l.Value)-1]))
 		case l.GetType().Equals(ast.BasicTypePtr(ast.Float)):
 			g.writeln(id + ":")
-			g.writeln(".double " + l.Value)
+			value, _ := lexer.ParseFloatLiteral(l.Value)
+			g.writeln(".double " + strconv.FormatFloat(value, 'e', -1, 64))
 		default:
 			err = errors.Join(err, generatorError(l.Position, "can't generate constant of type %s", l.GetType().String()))
 		}
@@ -193,11 +466,19 @@ func (g *Generator) programHeaders() {
 
 func (g *Generator) generateBuiltinExterns() {
 	g.writeln(".extern malloc")
+	g.writeln(".extern realloc")
 	g.writeln(".extern free")
+	g.writeln(".extern memcpy")
+	g.writeln(".extern memmove")
+	g.writeln(".extern memchr")
+	g.writeln(".extern strlen")
+	g.writeln(".extern strcmp")
+	g.writeln(".extern snprintf")
 }
 
 func (g *Generator) VisitProgram(p *ast.Program) error {
 	var err error
+	g.assignSymbols(p)
 	g.programHeaders()
 	g.writeln("# external functions")
 	for _, decl := range p.ExternalDeclarations {
@@ -217,35 +498,140 @@ func (g *Generator) VisitExternalDeclaration(d *ast.ExternalDeclaration) error {
 	return nil
 }
 
+func (g *Generator) VisitStructDeclaration(d *ast.StructDeclaration) error { return nil }
+func (g *Generator) VisitEnumDeclaration(d *ast.EnumDeclaration) error     { return nil }
+func (g *Generator) VisitConstant(c *ast.Constant) error                   { return nil }
+
+// VisitGlobal writes the initial value of the global to the data section.
+// Globals without a folded value are zero-initialized in the bss section.
+func (g *Generator) VisitGlobal(gl *ast.Global) error {
+	if gl.Folded == nil {
+		g.writeln(".bss")
+		g.writeln(".balign 8")
+		g.writefln("%s:", g.globals[gl.Identifier])
+		g.writefln(".zero %d", gl.Type.Size())
+		return nil
+	}
+
+	g.writeln(".data")
+	g.writeln(".balign 8")
+	g.writefln("%s:", g.globals[gl.Identifier])
+	switch *gl.Folded.GetType().(*ast.BasicType) { // enums are folded to ints
+	case ast.Int, ast.I8, ast.I16, ast.I32, ast.U8, ast.U16, ast.U32, ast.U64:
+		value, _ := lexer.ParseUnsignedLiteral(gl.Folded.Value)
+		g.writefln("%s %d", dataDirectives[gl.Folded.GetType().Size()], value)
+	case ast.Bool:
+		if gl.Folded.Value == "true" {
+			g.writeln(".quad 1")
+		} else {
+			g.writeln(".quad 0")
+		}
+	case ast.Float:
+		value, _ := lexer.ParseFloatLiteral(gl.Folded.Value)
+		g.writeln(".double " + strconv.FormatFloat(value, 'e', -1, 64))
+	case ast.String:
+		label := g.constLabel()
+		g.constants[label] = gl.Folded
+		g.writefln(".quad %s", label)
+	default:
+		return generatorError(gl.Identifier.Position, "can't generate global of type %s", gl.Type.String())
+	}
+	return nil
+}
+
+// dataDirectives holds the directives emitting integers of each size.
+var dataDirectives = map[int]string{1: ".byte", 2: ".short", 4: ".long", 8: ".quad"}
+
 func (g *Generator) generatePrologue(offset int) {
 	g.writeln("# function prologue")
-	g.writefln("%s:", g.ctx.currentDecl.Identifier.Name)
+	g.writefln("%s:", g.symbols[g.ctx.currentDecl.Identifier])
 	g.writeln("push %rbp") // aligned at this point
 	g.writeln("mov %rsp, %rbp")
 	g.writefln("sub $%d, %%rsp", offset) // offset is aligned by 16
+	if g.ctx.savedRbx != 0 {
+		g.writefln("mov %%rbx, -%d(%%rbp)", g.ctx.savedRbx)
+	}
 	g.writeln("")
 }
 
 func (g *Generator) generateEpilogue() {
 	g.writeln("# function epilogue")
+	g.restoreRbx()
 	g.writeln("leave")
 	g.writeln("ret")
 	g.writeln("")
 }
 
+func (g *Generator) restoreRbx() {
+	if g.ctx.savedRbx != 0 {
+		g.writefln("mov -%d(%%rbp), %%rbx", g.ctx.savedRbx)
+	}
+}
+
 func (g *Generator) VisitDeclaration(d *ast.Declaration) error {
 	var err error
 	offset := g.newContext(d)
 	g.generatePrologue(offset)
+	if returnedThroughPointer(d.Type) {
+		// save the hidden pointer to the caller allocated return value
+		g.loadNthIntArg(g.ctx.intArgsGenerated, g.ctx.locals[d])
+		g.ctx.intArgsGenerated++
+	}
 	for _, arg := range d.Args {
 		err = errors.Join(err, arg.Accept(g))
 	}
 	g.writeln("")
 	err = errors.Join(err, d.Body.Accept(g))
+	g.generateReturnValue(d.Type, d.Body.GetType())
 	g.generateEpilogue()
 	return err
 }
 
+// generateReturnValue moves a return value from the value protocol to where
+// the caller expects it. Arrays and structs of the MEMORY class are copied to
+// the caller allocated space, whose address is returned in %rax, along with
+// the length of arrays in %rbx. The rest of the structs is returned in %rax,
+// %rdx, %xmm0 and %xmm1. Arrays returned as slices become slices, values of
+// other types are left untouched.
+func (g *Generator) generateReturnValue(t, valueType ast.Type) {
+	if _, isSlice := t.(*ast.SliceType); isSlice {
+		g.sliceOf(valueType)
+		return
+	}
+
+	if returnedThroughPointer(t) {
+		g.writeln("# return through hidden pointer")
+		returnOffset := g.ctx.locals[g.ctx.currentDecl]
+		g.writeln("mov %rax, %rsi")
+		g.writefln("mov -%d(%%rbp), %%rdi", returnOffset)
+		g.writefln("mov $%d, %%rcx", (t.Size()+7)/8)
+		g.writeln("rep movsq")
+		g.writefln("mov -%d(%%rbp), %%rax", returnOffset)
+		if arrayType, ok := t.(*ast.ArrayType); ok {
+			g.writefln("mov $%d, %%rbx", arrayType.Length)
+		}
+		return
+	}
+
+	structType, ok := t.(*ast.StructType)
+	if !ok {
+		return
+	}
+
+	g.writeln("# struct return")
+	g.writeln("mov %rax, %rcx")
+	intRegs, floatRegs := 0, 0
+	for i, isFloat := range eightbyteClasses(structType) {
+		if isFloat {
+			g.writefln("movsd %d(%%rcx), %s", i*8, returnFloatRegs[floatRegs])
+			floatRegs++
+		} else {
+			g.writefln("mov %d(%%rcx), %s", i*8, returnIntRegs[intRegs])
+			intRegs++
+		}
+	}
+}
+
 // storeNthIntArg expects the stored value in %rax. It stores it for according to
 // the System V AMD64 ABI.
 func (g *Generator) storeNthIntArg(n int) {
@@ -325,12 +711,37 @@ func (g *Generator) loadStackSlot(slotIndex, localOffset int, isFloat bool) {
 }
 
 // VisitArgument moves an incoming argument from its ABI register(s) to the stack.
-// Arrays and slices occupy two consecutive registers/locations: (length, pointer).
+// Arrays occupy two consecutive registers/locations: (pointer, length), slices
+// occupy three: (pointer, length, capacity).
+// Structs occupy one register/location per eightbyte, unless they are of the
+// MEMORY class or don't fit into the remaining registers, in which case they
+// are passed on the stack as a whole.
 func (g *Generator) VisitArgument(a *ast.Argument) error {
 	offset := g.ctx.locals[a.Identifier]
 
 	switch t := a.Type.(type) {
-	case *ast.BasicType, *ast.PointerType:
+	case *ast.StructType:
+		classes := eightbyteClasses(t)
+		ints, floats := countClasses(classes)
+		if passedInMemory(t) || g.ctx.intArgsGenerated+ints > 6 || g.ctx.floatArgsGenerated+floats > 8 {
+			for i := range classes {
+				g.loadStackSlot(g.ctx.stackSlotsGenerated, offset-i*8, false)
+				g.ctx.stackSlotsGenerated++
+			}
+			return nil
+		}
+
+		for i, isFloat := range classes {
+			if isFloat {
+				g.loadNthFloatArg(g.ctx.floatArgsGenerated, offset-i*8)
+				g.ctx.floatArgsGenerated++
+			} else {
+				g.loadNthIntArg(g.ctx.intArgsGenerated, offset-i*8)
+				g.ctx.intArgsGenerated++
+			}
+		}
+
+	case *ast.BasicType, *ast.PointerType, *ast.EnumType, *ast.FunctionType:
 		if t.Equals(ast.BasicTypePtr(ast.Float)) {
 			if g.ctx.floatArgsGenerated < 8 {
 				g.loadNthFloatArg(g.ctx.floatArgsGenerated, offset)
@@ -350,50 +761,46 @@ func (g *Generator) VisitArgument(a *ast.Argument) error {
 		}
 
 	case *ast.SliceType, *ast.ArrayType:
-		if g.ctx.intArgsGenerated < 6 {
-			g.loadNthIntArg(g.ctx.intArgsGenerated, offset)
-			g.ctx.intArgsGenerated++
-		} else {
-			g.loadStackSlot(g.ctx.stackSlotsGenerated, offset, false)
-			g.ctx.stackSlotsGenerated++
-		}
-
-		if g.ctx.intArgsGenerated < 6 {
-			g.loadNthIntArg(g.ctx.intArgsGenerated, offset-8)
-			g.ctx.intArgsGenerated++
-
-			if st, ok := t.(*ast.SliceType); ok && st.LengthIdentifier != nil {
-				lenOffset := g.ctx.locals[st.LengthIdentifier]
-				g.loadNthIntArg(g.ctx.intArgsGenerated-1, lenOffset)
-			}
-		} else {
-			g.loadStackSlot(g.ctx.stackSlotsGenerated, offset-8, false)
-			g.ctx.stackSlotsGenerated++
-			if st, ok := t.(*ast.SliceType); ok && st.LengthIdentifier != nil {
-				lenOffset := g.ctx.locals[st.LengthIdentifier]
-				g.loadStackSlot(g.ctx.stackSlotsGenerated-1, lenOffset, false)
+		for i := range argumentWords(t) {
+			if g.ctx.intArgsGenerated < 6 {
+				g.loadNthIntArg(g.ctx.intArgsGenerated, offset-i*8)
+				g.ctx.intArgsGenerated++
+			} else {
+				g.loadStackSlot(g.ctx.stackSlotsGenerated, offset-i*8, false)
+				g.ctx.stackSlotsGenerated++
 			}
 		}
 
+		if st, ok := t.(*ast.SliceType); ok && st.LengthIdentifier != nil {
+			lenOffset := g.ctx.locals[st.LengthIdentifier]
+			g.writefln("mov -%d(%%rbp), %%rax", offset-8)
+			g.writefln("mov %%rax, -%d(%%rbp)", lenOffset)
+		}
 	}
 	return nil
 }
 
-func (g *Generator) VisitBasicType(t *ast.BasicType) error     { return nil }
-func (g *Generator) VisitArrayType(t *ast.ArrayType) error     { return nil }
-func (g *Generator) VisitSliceType(t *ast.SliceType) error     { return nil }
-func (g *Generator) VisitPointerType(t *ast.PointerType) error { return nil }
+func (g *Generator) VisitBasicType(t *ast.BasicType) error       { return nil }
+func (g *Generator) VisitArrayType(t *ast.ArrayType) error       { return nil }
+func (g *Generator) VisitSliceType(t *ast.SliceType) error       { return nil }
+func (g *Generator) VisitPointerType(t *ast.PointerType) error   { return nil }
+func (g *Generator) VisitStructType(t *ast.StructType) error     { return nil }
+func (g *Generator) VisitEnumType(t *ast.EnumType) error         { return nil }
+func (g *Generator) VisitFunctionType(t *ast.FunctionType) error { return nil }
 
-// VisitReturn moves the return value to %rax/%xmm0 (and %rbx for slices/arrays) and returns.
+// VisitReturn moves the return value to %rax/%xmm0 (and %rbx for arrays, %rbx
+// and %rdx for slices) and returns.
 func (g *Generator) VisitReturn(r *ast.Return) error {
 	g.writeln("# return")
 	if r.Value != nil {
 		if err := r.Value.Accept(g); err != nil {
 			return err
 		}
+		g.generateReturnValue(g.ctx.currentDecl.Type, r.Value.GetType())
 	} else {
 		g.writeln("mov $0, %rax")
 	}
+	g.restoreRbx()
 	g.writeln("leave")
 	g.writeln("ret")
 	g.writeln("")
@@ -401,8 +808,9 @@ func (g *Generator) VisitReturn(r *ast.Return) error {
 }
 
 // VisitBind evaluates the right-hand value and stores it into the declared local.
-// The value protocol means slices and arrays always arrive as (%rax, %rbx),
-// so there is no need to inspect the value's type - only the declared binding type.
+// The value protocol means arrays always arrive as (%rax, %rbx) and slices as
+// (%rax, %rbx, %rdx), so the value's type only matters when an array is bound
+// as a slice.
 func (g *Generator) VisitBind(b *ast.Bind) error {
 	g.writeln("# bind")
 	offset := g.ctx.locals[b.Identifier]
@@ -421,24 +829,36 @@ func (g *Generator) VisitBind(b *ast.Bind) error {
 			g.writeln("# array copy")
 			g.copyArray(offset, (t.Size()+7)/8)
 		}
+	case *ast.StructType:
+		if lit, ok := b.Value.(*ast.Literal); ok && lit.Value == "0" {
+			g.writeln("# struct zero-init")
+			g.zeroArray(offset, (t.Size()+7)/8)
+		} else {
+			if err := b.Value.Accept(g); err != nil {
+				return err
+			}
+			g.writeln("# struct copy")
+			g.copyArray(offset, (t.Size()+7)/8)
+		}
 	case *ast.SliceType:
 		if err := b.Value.Accept(g); err != nil {
 			return err
 		}
 		g.writeln("# slice bind")
+		g.sliceOf(b.Value.GetType())
 		g.storeSlice(offset)
 		if t.LengthIdentifier != nil {
 			lenOffset := g.ctx.locals[t.LengthIdentifier]
 			g.writefln("mov %%rbx, -%d(%%rbp)", lenOffset)
 		}
-	case *ast.BasicType, *ast.PointerType:
+	case *ast.BasicType, *ast.PointerType, *ast.EnumType, *ast.FunctionType:
 		if err := b.Value.Accept(g); err != nil {
 			return err
 		}
 		if t.Equals(ast.BasicTypePtr(ast.Float)) {
 			g.storeFloatScalar(offset)
 		} else {
-			g.storeScalar(offset)
+			g.storeSized(t, local(offset))
 		}
 	default:
 		return generatorError(b.GetPosition(), "unexpected type %s", b.Identifier.Resolved.GetType().String())
@@ -458,8 +878,12 @@ func (g *Generator) VisitLiteral(l *ast.Literal) error {
 		return generatorError(l.Position, "literals of non-basic type are not supported")
 	}
 	switch *t {
-	case ast.Int:
-		g.writefln("mov $%s, %%rax", l.Value)
+	case ast.Int, ast.I8, ast.I16, ast.I32, ast.U8, ast.U16, ast.U32, ast.U64:
+		value, err := lexer.ParseUnsignedLiteral(l.Value)
+		if err != nil {
+			return generatorError(l.Position, "invalid integer literal %s", l.Value)
+		}
+		g.writefln("mov $%d, %%rax", value)
 	case ast.Bool:
 		switch l.Value {
 		case "false":
@@ -491,8 +915,28 @@ func (g *Generator) VisitLiteral(l *ast.Literal) error {
 //			  -> Float -> scalar in %xmm0
 //	ArrayType -> pointer in %rax, length in %rbx
 //	SliceType -> pointer in %rax, length in %rbx
+//	StructType -> pointer in %rax
+//	FunctionType -> pointer in %rax
+//
+// The name of a function evaluates to its address.
 func (g *Generator) VisitIdentifier(i *ast.Identifier) error {
+	if literal, isConstant := g.folded[i.Resolved]; isConstant {
+		return literal.Accept(g)
+	}
 	g.writeln("# identifier")
+	if g.externals[i.Resolved] {
+		g.writefln("mov %s@GOTPCREL(%%rip), %%rax", i.Name)
+		return nil
+	}
+	if symbol, isFunction := g.symbols[i.Resolved]; isFunction {
+		g.writefln("lea %s(%%rip), %%rax", symbol)
+		return nil
+	}
+	if label, isGlobal := g.globals[i.Resolved]; isGlobal {
+		g.writefln("lea %s(%%rip), %%rax", label)
+		g.loadIndirect(i.Resolved.GetType())
+		return nil
+	}
 	offset, exists := g.ctx.locals[i.Resolved]
 	if !exists {
 		return generatorError(i.Position, "unresolved identifier %q", i.Name)
@@ -507,11 +951,13 @@ func (g *Generator) VisitIdentifier(i *ast.Identifier) error {
 		g.writefln("mov $%d, %%rbx", t.Length)
 	case *ast.SliceType:
 		g.loadSlice(offset)
-	case *ast.BasicType, *ast.PointerType:
+	case *ast.StructType:
+		g.loadArrayAddr(offset)
+	case *ast.BasicType, *ast.PointerType, *ast.EnumType, *ast.FunctionType:
 		if t.Equals(ast.BasicTypePtr(ast.Float)) {
 			g.loadFloatScalar(offset)
 		} else {
-			g.loadScalar(offset)
+			g.loadSized(t, local(offset))
 		}
 	default:
 		return generatorError(i.GetPosition(), "unexpected type %s", i.Resolved.GetType().String())
@@ -523,9 +969,15 @@ func (g *Generator) VisitIdentifier(i *ast.Identifier) error {
 //
 // Arguments are evaluated and pushed to the stack so that register
 // allocations within each argument do not interfere with each other.
-// Arrays and slices each occupy two physical arguments: (length, pointer).
+// Arrays occupy two physical arguments: (pointer, length), slices occupy
+// three: (pointer, length, capacity). Arrays passed as slices become slices.
+// Structs occupy one physical argument per eightbyte and are either passed in
+// registers or on the stack as a whole.
 // Once all values are on the stack they are popped into the correct registers
 // in reverse push order.
+//
+// Struct return values are stored into a temporary owned by the call, whose
+// address is left in %rax.
 func (g *Generator) VisitCall(c *ast.Call) error {
 	g.writeln("# call")
 
@@ -542,9 +994,33 @@ func (g *Generator) VisitCall(c *ast.Call) error {
 	floatRegsUsed := 0
 	stackSlotsUsed := 0
 
-	for _, arg := range c.Arguments {
-		switch t := arg.GetType().(type) {
-		case *ast.BasicType, *ast.PointerType:
+	returnsInMemory := returnedThroughPointer(c.GetType())
+	if returnsInMemory {
+		intRegsUsed++ // hidden pointer to the return value in %rdi
+	}
+
+	for i := range c.Arguments {
+		switch t := g.parameterType(c, i).(type) {
+		case *ast.StructType:
+			classes := eightbyteClasses(t)
+			ints, floats := countClasses(classes)
+			if passedInMemory(t) || intRegsUsed+ints > 6 || floatRegsUsed+floats > 8 {
+				for range classes {
+					slots = append(slots, argDest{isFloat: false, isStack: true})
+					stackSlotsUsed++
+				}
+				continue
+			}
+			for _, isFloat := range classes {
+				if isFloat {
+					slots = append(slots, argDest{isFloat: true, isStack: false, regNum: floatRegsUsed})
+					floatRegsUsed++
+				} else {
+					slots = append(slots, argDest{isFloat: false, isStack: false, regNum: intRegsUsed})
+					intRegsUsed++
+				}
+			}
+		case *ast.BasicType, *ast.PointerType, *ast.EnumType, *ast.FunctionType:
 			isFloat := t.Equals(ast.BasicTypePtr(ast.Float))
 
 			if isFloat {
@@ -565,7 +1041,7 @@ func (g *Generator) VisitCall(c *ast.Call) error {
 				}
 			}
 		case *ast.ArrayType, *ast.SliceType:
-			for range 2 {
+			for range argumentWords(t) {
 				if intRegsUsed < 6 {
 					slots = append(slots, argDest{isFloat: false, isStack: false, regNum: intRegsUsed})
 					intRegsUsed++
@@ -579,20 +1055,43 @@ func (g *Generator) VisitCall(c *ast.Call) error {
 		}
 	}
 
+	// any other called value than a named function or pointer is computed
+	// before the arguments, which could clobber it, and kept under them
+	identifier, isDirect := c.Function.(*ast.Identifier)
+	if !isDirect {
+		if err := c.Function.Accept(g); err != nil {
+			return err
+		}
+		g.pushIntReg("%rax")
+	}
+
+	// align the stack upfront, so that it stays aligned once only the stack
+	// arguments remain, padding after them would shift their offsets
+	misalignment := (g.ctx.stackDepth + stackSlotsUsed*8) % 16
+	pad := 16 - misalignment
+	if misalignment != 0 {
+		g.adjustStack(pad)
+	}
+
 	// evaluate everything right-to-left, pushing everything onto the stack
-	for _, arg := range slices.Backward(c.Arguments) {
+	for i, arg := range slices.Backward(c.Arguments) {
 		if err := arg.Accept(g); err != nil {
 			return err
 		}
-		switch t := arg.GetType().(type) {
-		case *ast.BasicType, *ast.PointerType:
+		switch t := g.parameterType(c, i).(type) {
+		case *ast.BasicType, *ast.PointerType, *ast.EnumType, *ast.FunctionType:
 			if t.Equals(ast.BasicTypePtr(ast.Float)) {
 				g.writeln("movq %xmm0, %rax")
 			}
 			g.pushIntReg("%rax")
-		case *ast.SliceType, *ast.ArrayType:
+		case *ast.SliceType:
+			g.sliceOf(arg.GetType())
+			g.pushValue(t)
+		case *ast.ArrayType:
 			g.pushIntReg("%rbx")
 			g.pushIntReg("%rax")
+		case *ast.StructType:
+			g.pushStruct(t)
 		default:
 			panic("unexpected argument type")
 		}
@@ -635,33 +1134,81 @@ func (g *Generator) VisitCall(c *ast.Call) error {
 		g.adjustStack(-adjustment)
 	}
 
-	// align before call
-	misalignment := g.ctx.stackDepth % 16
-	pad := 16 - misalignment
-	if misalignment != 0 {
-		g.adjustStack(pad)
+	if returnsInMemory {
+		g.writefln("lea -%d(%%rbp), %%rdi", g.ctx.locals[c])
 	}
 
-	if g.externals[c.Identifier.Resolved] {
+	if !isDirect {
+		// the pointer lies under the stack arguments and the padding
+		offset := stackSlotsUsed * 8
+		if misalignment != 0 {
+			offset += pad
+		}
+		g.writefln("mov %d(%%rsp), %%rax", offset)
+		g.writeln("call *%rax")
+	} else if g.externals[identifier.Resolved] {
 		g.writefln("mov $%d, %%rax", floatRegsUsed)
-		g.writefln("call %s@PLT", c.Identifier.Name)
-	} else {
+		g.writefln("call %s@PLT", identifier.Name)
+	} else if symbol, isFunction := g.symbols[identifier.Resolved]; isFunction {
 		g.writeln("xor %rax, %rax")
-		g.writefln("call %s", c.Identifier.Name)
+		g.writefln("call %s", symbol)
+	} else {
+		// loading the pointer only touches %rax, the arguments stay in place
+		if err := identifier.Accept(g); err != nil {
+			return err
+		}
+		g.writeln("call *%rax")
+	}
+
+	// clean up the remaining stack arguments
+	if stackSlotsUsed > 0 {
+		g.adjustStack(-stackSlotsUsed * 8)
 	}
 
 	if misalignment != 0 {
 		g.adjustStack(-pad)
 	}
+	if !isDirect {
+		g.adjustStack(-8)
+	}
+	g.normalize(c.GetType()) // C leaves the upper bytes of small integers undefined
 
-	// clean up the remaining stack arguments
-	if stackSlotsUsed > 0 {
-		g.adjustStack(-stackSlotsUsed * 8)
+	if structType, ok := c.GetType().(*ast.StructType); ok && !returnsInMemory {
+		offset := g.ctx.locals[c]
+		intRegs, floatRegs := 0, 0
+		for i, isFloat := range eightbyteClasses(structType) {
+			if isFloat {
+				g.writefln("movsd %s, -%d(%%rbp)", returnFloatRegs[floatRegs], offset-i*8)
+				floatRegs++
+			} else {
+				g.writefln("mov %s, -%d(%%rbp)", returnIntRegs[intRegs], offset-i*8)
+				intRegs++
+			}
+		}
+		g.loadArrayAddr(offset)
 	}
 
 	return nil
 }
 
+// parameterType returns the type the i-th argument of the call is passed as,
+// which is the declared parameter type unless the argument is variadic.
+func (g *Generator) parameterType(c *ast.Call, i int) ast.Type {
+	if parameters := c.Function.GetType().(*ast.FunctionType).Parameters; i < len(parameters) {
+		return parameters[i]
+	}
+	return c.Arguments[i].GetType()
+}
+
+// argumentWords returns the number of eightbytes an array or slice argument
+// is passed in.
+func argumentWords(t ast.Type) int {
+	if _, isSlice := t.(*ast.SliceType); isSlice {
+		return 3 // pointer, length, capacity
+	}
+	return 2 // pointer, length
+}
+
 func (g *Generator) VisitSeparated(s *ast.Separated) error {
 	g.writeln("# separated")
 	return s.Value.Accept(g)
@@ -671,13 +1218,7 @@ func (g *Generator) VisitUnary(u *ast.Unary) error {
 	g.writeln("# unary")
 
 	if u.Operator == ast.AddressOf {
-		id, ok := u.Value.(*ast.Identifier)
-		if !ok {
-			return generatorError(u.GetPosition(), "can only take address of identifiers")
-		}
-		offset := g.ctx.locals[id.Resolved] // identifier already resolved
-		g.writefln("lea -%d(%%rbp), %%rax", offset)
-		return nil
+		return g.generateAddress(u.Value)
 	}
 
 	if err := u.Value.Accept(g); err != nil {
@@ -690,11 +1231,15 @@ func (g *Generator) VisitUnary(u *ast.Unary) error {
 			g.writeln("mulsd %xmm1, %xmm0")
 		} else {
 			g.writeln("imul $-1, %rax")
+			g.normalize(u.Value.GetType())
 		}
 	case ast.LogicNegation:
 		g.writeln("cmp $0, %rax")
 		g.writeln("sete %al")
 		g.writeln("movzbq %al, %rax")
+	case ast.BitNot:
+		g.writeln("not %rax")
+		g.normalize(u.Value.GetType())
 	default:
 		return generatorError(u.Position, "unknown unary operator")
 	}
@@ -703,8 +1248,20 @@ func (g *Generator) VisitUnary(u *ast.Unary) error {
 
 // generateBinaryOperator emits the instruction(s) for the given operator.
 // Expects: left operand in %rax, right operand in %rbx.
-func (g *Generator) generateBinaryOperator(o ast.BinaryOperator) error {
+func (g *Generator) generateBinaryOperator(o ast.BinaryOperator, t ast.Type) error {
+	if pointerType, ok := t.(*ast.PointerType); ok {
+		return g.generatePointerOperator(o, pointerType)
+	}
+	if t.Equals(ast.BasicTypePtr(ast.String)) {
+		return g.generateStringOperator(o)
+	}
+
 	g.writeln("# non-float binary operator")
+	// unsigned integers are compared by the below and above conditions
+	less, greater := "l", "g"
+	if ast.IsUnsigned(t) {
+		less, greater = "b", "a"
+	}
 	switch o {
 	case ast.Addition:
 		g.writeln("add %rbx, %rax")
@@ -713,61 +1270,150 @@ func (g *Generator) generateBinaryOperator(o ast.BinaryOperator) error {
 	case ast.Multiplication:
 		g.writeln("imul %rbx, %rax")
 	case ast.Division:
-		g.writeln("cltd")
-		g.writeln("idiv %rbx")
+		g.generateDivision(t)
 	case ast.Modulo:
-		g.writeln("cqto")
-		g.writeln("idiv %rbx")
+		g.generateDivision(t)
 		g.writeln("mov %rdx, %rax")
 	case ast.Equality:
 		g.writeln("cmp %rbx, %rax")
 		g.writeln("sete %al")
 		g.writeln("movzbq %al, %rax")
+		return nil
 	case ast.Inequality:
 		g.writeln("cmp %rbx, %rax")
 		g.writeln("setne %al")
 		g.writeln("movzbq %al, %rax")
+		return nil
 	case ast.Less:
 		g.writeln("cmp %rbx, %rax")
-		g.writeln("setl %al")
+		g.writefln("set%s %%al", less)
 		g.writeln("movzbq %al, %rax")
+		return nil
 	case ast.Greater:
 		g.writeln("cmp %rbx, %rax")
-		g.writeln("setg %al")
+		g.writefln("set%s %%al", greater)
 		g.writeln("movzbq %al, %rax")
+		return nil
 	case ast.LessEqual:
 		g.writeln("cmp %rbx, %rax")
-		g.writeln("setle %al")
+		g.writefln("set%se %%al", less)
 		g.writeln("movzbq %al, %rax")
+		return nil
 	case ast.GreaterEqual:
 		g.writeln("cmp %rbx, %rax")
-		g.writeln("setge %al")
+		g.writefln("set%se %%al", greater)
 		g.writeln("movzbq %al, %rax")
+		return nil
 	case ast.ShiftLeft:
 		g.writeln("mov %rbx, %rcx")
 		g.writeln("shl %cl, %rax")
 	case ast.ShiftRight:
 		g.writeln("mov %rbx, %rcx")
-		g.writeln("sar %cl, %rax")
-	case ast.LogicAnd:
+		if ast.IsUnsigned(t) {
+			g.writeln("shr %cl, %rax")
+		} else {
+			g.writeln("sar %cl, %rax")
+		}
+	case ast.BitAnd:
 		g.writeln("and %rbx, %rax")
-	case ast.LogicOr:
+	case ast.BitOr:
 		g.writeln("or %rbx, %rax")
+	case ast.BitXor:
+		g.writeln("xor %rbx, %rax")
 	default:
 		return fmt.Errorf("operator %s not implemented", o.String())
 	}
+	g.normalize(t) // wrap around sized integers
 	return nil
 }
 
-// the same as with non-float types but with %xmm0 and %xmm1
-func (g *Generator) generateFloatBinaryOperator(o ast.BinaryOperator) error {
-	g.writeln("# float binary operator")
-	switch o {
-	case ast.Addition:
-		g.writeln("addsd %xmm1, %xmm0")
-	case ast.Subtraction:
-		g.writeln("subsd %xmm1, %xmm0")
-	case ast.Multiplication:
+// generateDivision divides %rax by %rbx, leaving the quotient in %rax and the
+// remainder in %rdx.
+func (g *Generator) generateDivision(t ast.Type) {
+	if ast.IsUnsigned(t) {
+		g.writeln("xor %edx, %edx")
+		g.writeln("div %rbx")
+	} else {
+		g.writeln("cqto")
+		g.writeln("idiv %rbx")
+	}
+}
+
+// generateStringOperator compares the strings in %rax and %rbx by their bytes.
+func (g *Generator) generateStringOperator(o ast.BinaryOperator) error {
+	g.writeln("# string binary operator")
+	g.writeln("mov %rax, %rdi")
+	g.writeln("mov %rbx, %rsi")
+	g.callAligned("strcmp")
+	g.writeln("cmp $0, %eax")
+	switch o {
+	case ast.Equality:
+		g.writeln("sete %al")
+	case ast.Inequality:
+		g.writeln("setne %al")
+	default:
+		return fmt.Errorf("operator %s not implemented for strings", o.String())
+	}
+	g.writeln("movzbq %al, %rax")
+	return nil
+}
+
+// generatePointerOperator generates arithmetic and comparisons with the
+// pointer in %rax. The int offset in %rbx is scaled by the size of the
+// pointed-to type, and addresses are compared unsigned.
+func (g *Generator) generatePointerOperator(o ast.BinaryOperator, t *ast.PointerType) error {
+	g.writeln("# pointer binary operator")
+	switch o {
+	case ast.Addition, ast.Subtraction:
+		if o == ast.Subtraction {
+			g.writeln("neg %rbx")
+		}
+		switch size := t.Inner.Size(); size {
+		case 1, 2, 4, 8:
+			g.writefln("lea (%%rax, %%rbx, %d), %%rax", size)
+		default:
+			g.writefln("imul $%d, %%rbx", size)
+			g.writeln("lea (%rax, %rbx), %rax")
+		}
+	case ast.Equality:
+		g.writeln("cmp %rbx, %rax")
+		g.writeln("sete %al")
+		g.writeln("movzbq %al, %rax")
+	case ast.Inequality:
+		g.writeln("cmp %rbx, %rax")
+		g.writeln("setne %al")
+		g.writeln("movzbq %al, %rax")
+	case ast.Less:
+		g.writeln("cmp %rbx, %rax")
+		g.writeln("setb %al")
+		g.writeln("movzbq %al, %rax")
+	case ast.Greater:
+		g.writeln("cmp %rbx, %rax")
+		g.writeln("seta %al")
+		g.writeln("movzbq %al, %rax")
+	case ast.LessEqual:
+		g.writeln("cmp %rbx, %rax")
+		g.writeln("setbe %al")
+		g.writeln("movzbq %al, %rax")
+	case ast.GreaterEqual:
+		g.writeln("cmp %rbx, %rax")
+		g.writeln("setae %al")
+		g.writeln("movzbq %al, %rax")
+	default:
+		return fmt.Errorf("operator %s not implemented for pointers", o.String())
+	}
+	return nil
+}
+
+// the same as with non-float types but with %xmm0 and %xmm1
+func (g *Generator) generateFloatBinaryOperator(o ast.BinaryOperator) error {
+	g.writeln("# float binary operator")
+	switch o {
+	case ast.Addition:
+		g.writeln("addsd %xmm1, %xmm0")
+	case ast.Subtraction:
+		g.writeln("subsd %xmm1, %xmm0")
+	case ast.Multiplication:
 		g.writeln("mulsd %xmm1, %xmm0")
 	case ast.Division:
 		g.writeln("divsd %xmm1, %xmm0")
@@ -806,6 +1452,12 @@ func (g *Generator) generateFloatBinaryOperator(o ast.BinaryOperator) error {
 // by storing the intermediate value on the stack and using %xmm0 and %xmm1)
 func (g *Generator) VisitBinary(u *ast.Binary) error {
 	g.writeln("# binary")
+	if u.Operator == ast.LogicAnd || u.Operator == ast.LogicOr {
+		return g.generateShortCircuit(u)
+	}
+	if ast.IsAllocation(u) {
+		return g.generateConcat(u)
+	}
 	if u.Left.GetType().Equals(ast.BasicTypePtr(ast.Float)) {
 		if err := u.Left.Accept(g); err != nil {
 			return err
@@ -827,8 +1479,30 @@ func (g *Generator) VisitBinary(u *ast.Binary) error {
 		}
 		g.writeln("mov %rax, %rbx")
 		g.popIntReg("%rax")
-		return g.generateBinaryOperator(u.Operator)
+		return g.generateBinaryOperator(u.Operator, u.Left.GetType())
+	}
+}
+
+// generateShortCircuit generates && and || so that the right operand is only
+// evaluated when the left one doesn't decide the result already. Booleans are
+// either 0 or 1, so the left operand is the result when the jump is taken.
+func (g *Generator) generateShortCircuit(u *ast.Binary) error {
+	endLabel := g.label()
+
+	if err := u.Left.Accept(g); err != nil {
+		return err
 	}
+	g.writeln("cmp $0, %rax")
+	if u.Operator == ast.LogicAnd {
+		g.writefln("je %s", endLabel)
+	} else {
+		g.writefln("jne %s", endLabel)
+	}
+	if err := u.Right.Accept(g); err != nil {
+		return err
+	}
+	g.writefln("%s:", endLabel)
+	return nil
 }
 
 func (g *Generator) VisitBlock(b *ast.Block) error {
@@ -876,29 +1550,183 @@ func (g *Generator) VisitCondition(c *ast.Condition) error {
 	return nil
 }
 
-// containerLoad loads %rcx with the base address of the indexed container:
-// a pointer value for slices and array arguments, or an effective address
-// for locally-allocated arrays.
-func (g *Generator) containerLoad(id *ast.Identifier) error {
-	offset, exists := g.ctx.locals[id.Resolved]
-	if !exists {
-		return generatorError(id.Position, "unresolved identifier %q", id.Name)
+// containerLoad evaluates an indexed array or slice, leaving the pointer to
+// its first element in %rcx and its length in %rbx.
+func (g *Generator) containerLoad(v ast.Primary) error {
+	if err := v.Accept(g); err != nil {
+		return err
 	}
-	_, isSlice := id.Resolved.GetType().(*ast.SliceType)
-	_, isArray := id.Resolved.GetType().(*ast.ArrayType)
-	if isSlice || (isArray && g.isArgument(id.Resolved)) {
-		g.writefln("mov -%d(%%rbp), %%rcx", offset)
-	} else {
-		g.writefln("lea -%d(%%rbp), %%rcx", offset)
+	if v.GetType().Equals(ast.BasicTypePtr(ast.String)) && g.BoundsChecks {
+		g.stringLength()
 	}
+	g.writeln("mov %rax, %rcx")
 	return nil
 }
 
+// stringLength leaves the length of the string in %rax in %rbx, keeping the
+// string in %rax. Strings don't store their length, so it is counted.
+func (g *Generator) stringLength() {
+	g.pushIntReg("%rax")
+	g.writeln("mov %rax, %rdi")
+	g.callAligned("strlen")
+	g.writeln("mov %rax, %rbx")
+	g.popIntReg("%rax")
+}
+
+// terminatorCheck checks that the bytes at %rax with the length in %rbx and
+// the capacity in %rdx contain the zero byte which terminates a string,
+// keeping the bytes in %rax. The terminator may be past the length as long as
+// it is within the capacity, like the one of bytes converted from a string.
+// A missing terminator is reported as an out of bounds index at the length.
+// Nothing is generated unless bounds checks are enabled.
+func (g *Generator) terminatorCheck(position lexer.Position) {
+	if !g.BoundsChecks {
+		return
+	}
+
+	okLabel := g.label()
+	g.writeln("# terminator check")
+	g.writeln("cmp %rbx, %rdx")
+	g.writeln("cmovb %rbx, %rdx") // the bytes searched, at least the length
+	g.pushIntReg("%rax")
+	g.pushIntReg("%rbx")
+	g.writeln("mov %rax, %rdi")
+	g.writeln("xor %esi, %esi")
+	g.callAligned("memchr")
+	g.writeln("mov %rax, %rcx")
+	g.popIntReg("%rdx")
+	g.popIntReg("%rax")
+	g.writeln("test %rcx, %rcx")
+	g.writefln("jne %s", okLabel)
+
+	g.writeln("mov %rdx, %rsi")
+	g.writefln("lea %s(%%rip), %%rdi", g.positionLabel(position))
+	g.writeln("call .bounds_check_failure")
+	g.writefln("%s:", okLabel)
+}
+
+// positionLabel returns the label of a string constant holding the position,
+// which is reported by failed runtime checks.
+func (g *Generator) positionLabel(position lexer.Position) string {
+	label := g.constLabel()
+	g.constants[label] = &ast.Literal{Value: `"` + position.String() + `"`}
+	g.constants[label].SetType(ast.BasicTypePtr(ast.String))
+	return label
+}
+
+// boundsCheck checks the index in %rdx against the container length in %rbx,
+// aborting the program with the position of the indexing expression if it is
+// out of bounds. Negative indexes are caught by the unsigned comparison.
+// Nothing is generated unless bounds checks are enabled.
+func (g *Generator) boundsCheck(position lexer.Position) {
+	if !g.BoundsChecks {
+		return
+	}
+
+	okLabel := g.label()
+	g.writeln("# bounds check")
+	g.writeln("mov %rbx, %rsi")
+	g.writeln("cmp %rsi, %rdx")
+	g.writefln("jb %s", okLabel)
+
+	g.writefln("lea %s(%%rip), %%rdi", g.positionLabel(position))
+	g.writeln("call .bounds_check_failure")
+	g.writefln("%s:", okLabel)
+}
+
+// generateBoundsCheckFailure generates the routine called by a failed bounds
+// check. It expects the position string in %rdi, the index in %rdx and the
+// length in %rsi, flushes the pending output, prints them to stderr and aborts.
+func (g *Generator) generateBoundsCheckFailure() {
+	g.writeln("")
+	g.writeln("# bounds check failure")
+	g.writeln(".bounds_check_failure:")
+	g.writeln("and $-16, %rsp")
+	g.writeln("push %rdi")
+	g.writeln("push %rsi")
+	g.writeln("push %rdx")
+	g.writeln("push %rdx") // keeps the stack aligned
+	g.writeln("xor %rdi, %rdi")
+	g.writeln("call fflush@PLT")
+	g.writeln("pop %rdx")
+	g.writeln("pop %rdx")
+	g.writeln("pop %rsi")
+	g.writeln("pop %rdi")
+	g.writeln("mov %rsi, %r8")
+	g.writeln("mov %rdx, %rcx")
+	g.writeln("mov %rdi, %rdx")
+	g.writeln("mov stderr(%rip), %rdi")
+	g.writeln("lea .bounds_check_format(%rip), %rsi")
+	g.writeln("xor %rax, %rax")
+	g.writeln("call fprintf@PLT")
+	g.writeln("call abort@PLT")
+	g.writeln("")
+	g.writeln(".section .rodata")
+	g.writeln(".bounds_check_format:")
+	g.writeln(`.asciz "%s: index %ld out of bounds for length %ld\n"`)
+}
+
+// sliceBoundsCheck checks the bounds in %rsi and %rdx against the container
+// length in %rbx, so that 0 <= low <= high <= length. Negative bounds are
+// caught by the unsigned comparisons. Unlike indexes, slice bounds are checked
+// even without bounds checks enabled, because a slice with a negative length
+// would corrupt everything done with it later.
+func (g *Generator) sliceBoundsCheck(position lexer.Position) {
+	g.slicing = true
+	failLabel := g.label()
+	okLabel := g.label()
+	g.writeln("# slice bounds check")
+	g.writeln("cmp %rbx, %rdx")
+	g.writefln("ja %s", failLabel)
+	g.writeln("cmp %rdx, %rsi")
+	g.writefln("jbe %s", okLabel)
+	g.writefln("%s:", failLabel)
+
+	g.writefln("lea %s(%%rip), %%rdi", g.positionLabel(position))
+	g.writeln("mov %rbx, %rcx")
+	g.writeln("call .slice_bounds_failure")
+	g.writefln("%s:", okLabel)
+}
+
+// generateSliceBoundsFailure generates the routine called by a failed slice
+// bounds check. It expects the position string in %rdi, the bounds in %rsi and
+// %rdx and the length in %rcx, flushes the pending output, prints them to
+// stderr and aborts.
+func (g *Generator) generateSliceBoundsFailure() {
+	g.writeln("")
+	g.writeln("# slice bounds failure")
+	g.writeln(".text")
+	g.writeln(".slice_bounds_failure:")
+	g.writeln("and $-16, %rsp")
+	g.writeln("push %rdi")
+	g.writeln("push %rsi")
+	g.writeln("push %rdx")
+	g.writeln("push %rcx")
+	g.writeln("xor %rdi, %rdi")
+	g.writeln("call fflush@PLT")
+	g.writeln("pop %r9")
+	g.writeln("pop %r8")
+	g.writeln("pop %rcx")
+	g.writeln("pop %rdx")
+	g.writeln("mov stderr(%rip), %rdi")
+	g.writeln("lea .slice_bounds_format(%rip), %rsi")
+	g.writeln("xor %rax, %rax")
+	g.writeln("call fprintf@PLT")
+	g.writeln("call abort@PLT")
+	g.writeln("")
+	g.writeln(".section .rodata")
+	g.writeln(".slice_bounds_format:")
+	g.writeln(`.asciz "%s: slice bounds [%ld:%ld] out of range for length %ld\n"`)
+}
+
 // VisitAssignment generates code for assigning a value to a scalar or indexed target.
 func (g *Generator) VisitAssignment(a *ast.Assignment) error {
 	g.writeln("# assignment")
 	switch target := a.Target.(type) {
 	case *ast.Identifier:
+		if _, isGlobal := g.globals[target.Resolved]; isGlobal {
+			return g.generateIndirectAssignment(a)
+		}
 		offset, exists := g.ctx.locals[target.Resolved]
 		if !exists {
 			return generatorError(target.Position, "unresolved identifier %q", target.Name)
@@ -917,10 +1745,22 @@ func (g *Generator) VisitAssignment(a *ast.Assignment) error {
 				g.writeln("# array copy assignment")
 				g.copyArray(offset, (t.Size()+7)/8)
 			}
+		case *ast.StructType:
+			if lit, ok := a.Value.(*ast.Literal); ok && lit.Value == "0" {
+				g.writeln("# struct zero-assignment")
+				g.zeroArray(offset, (t.Size()+7)/8)
+			} else {
+				if err := a.Value.Accept(g); err != nil {
+					return err
+				}
+				g.writeln("# struct copy assignment")
+				g.copyArray(offset, (t.Size()+7)/8)
+			}
 		case *ast.SliceType:
 			if err := a.Value.Accept(g); err != nil {
 				return err
 			}
+			g.sliceOf(a.Value.GetType())
 			g.storeSlice(offset)
 			if t.LengthIdentifier != nil {
 				lenOffset := g.ctx.locals[t.LengthIdentifier]
@@ -933,146 +1773,329 @@ func (g *Generator) VisitAssignment(a *ast.Assignment) error {
 			if t.Equals(ast.BasicTypePtr(ast.Float)) {
 				g.storeFloatScalar(offset)
 			} else {
-				g.storeScalar(offset)
+				g.storeSized(t, local(offset))
 			}
 		}
-	case *ast.Index:
-		if err := a.Value.Accept(g); err != nil {
-			return err
-		}
-		g.pushValue(a.Value.GetType()) // save value
-
-		if err := target.Index.Accept(g); err != nil {
-			return err
-		}
-		g.pushIntReg("%rax") // save index
-
-		if err := g.containerLoad(target.Identifier); err != nil {
-			return err
-		}
-
-		g.popIntReg("%rdx")           // index
-		g.popValue(a.Value.GetType()) // value
-
-		if a.Value.GetType().Equals(ast.BasicTypePtr(ast.Float)) {
-			g.writefln("mov %%xmm0, (%%rcx, %%rdx, %d)", target.GetType().Size())
-		} else {
-			g.writefln("mov %%rax, (%%rcx, %%rdx, %d)", target.GetType().Size())
-		}
-
-	case *ast.Dereference:
-		// evaluate value
-		if err := a.Value.Accept(g); err != nil {
-			return err
-		}
-		// save scalar value
-		g.pushValue(a.Value.GetType())
-		// evaluate pointer to %rax
-		if err := target.Value.Accept(g); err != nil {
-			return err
-		}
-
-		if a.Value.GetType().Equals(ast.BasicTypePtr(ast.Float)) {
-			g.popFloatReg("%xmm0")
-			g.writeln("movq %xmm0, (%rax)")
-		} else {
-			g.popIntReg("%rbx")
-			g.writeln("mov %rbx, (%rax)")
-		}
-
+	case *ast.Index, *ast.FieldAccess, *ast.Dereference:
+		return g.generateIndirectAssignment(a)
 	default:
 		return generatorError(a.Position, "invalid assignment target")
 	}
 	return nil
 }
 
+// generateIndirectAssignment stores the assigned value through the address of
+// the target.
+func (g *Generator) generateIndirectAssignment(a *ast.Assignment) error {
+	t := a.Target.GetType()
+	if err := a.Value.Accept(g); err != nil {
+		return err
+	}
+	if _, isSlice := t.(*ast.SliceType); isSlice {
+		g.sliceOf(a.Value.GetType())
+	}
+	g.pushValue(t) // save value
+	if err := g.generateAddress(a.Target); err != nil {
+		return err
+	}
+	g.writeln("mov %rax, %rcx")
+	g.popValue(t)
+	g.storeIndirect(t)
+	return nil
+}
+
+// VisitDereference loads the value the pointer points to. Arrays and structs
+// are left in place and represented by their address.
 func (g *Generator) VisitDereference(d *ast.Dereference) error {
 	if err := d.Value.Accept(g); err != nil {
 		return err
 	}
+	g.loadIndirect(d.GetType())
+	return nil
+}
 
-	if d.GetType().Equals(ast.BasicTypePtr(ast.Float)) {
-		g.writeln("movsd (%rax), %xmm0")
+// zeroLoopValue sets the value of a loop which produced no result of its own.
+func (g *Generator) zeroLoopValue(t ast.Type) {
+	if t.Equals(ast.BasicTypePtr(ast.Float)) {
+		g.writeln("mov $0, %rax")
+		g.writeln("movq %rax, %xmm0")
+		g.writeln("mov $0, %rax")
 	} else {
-		g.writeln("mov (%rax), %rax")
+		g.writeln("mov $0, %rax")
 	}
-
-	return nil
 }
 
 func (g *Generator) VisitLoop(l *ast.Loop) error {
 	g.writeln("# for loop")
 	startLabel := g.label()
 	endLabel := g.label()
+	breakLabel := g.label()
 
 	// default if body never runs
-	if l.GetType().Equals(ast.BasicTypePtr(ast.Float)) {
-		g.writeln("mov $0, %rax")
-		g.writeln("movq %rax, %xmm0")
-		g.writeln("mov $0, %rax")
-	} else {
-		g.writeln("mov $0, %rax")
-	}
+	g.zeroLoopValue(l.GetType())
 
 	g.writefln("%s:", startLabel)
 	g.pushValue(l.GetType()) // save last result before condition clobbers it
+	savedDepth := g.ctx.stackDepth
 	if err := l.Condition.Accept(g); err != nil {
 		return err
 	}
 	g.writeln("cmp $1, %rax")
 	g.writefln("jne %s", endLabel) // exit with saved value on stack
 	g.popValue(l.GetType())        // discard saved - body will produce new value
-	if err := l.Body.Accept(g); err != nil {
+
+	g.ctx.loops = append(g.ctx.loops, loopContext{
+		continueLabel: startLabel,
+		breakLabel:    breakLabel,
+		stackDepth:    g.ctx.stackDepth,
+		valueType:     l.GetType(),
+	})
+	err := l.Body.Accept(g)
+	g.ctx.loops = g.ctx.loops[:len(g.ctx.loops)-1]
+	if err != nil {
 		return err
 	}
+
 	g.writefln("jmp %s", startLabel)
 	g.writefln("%s:", endLabel)
-	g.popValue(l.GetType()) // restore last body result (or 0 for no iterations)
+	g.ctx.stackDepth = savedDepth // the saved result is still on the stack here
+	g.popValue(l.GetType())       // restore last body result (or 0 for no iterations)
+	g.writefln("%s:", breakLabel)
 	return nil
 }
 
-func (g *Generator) VisitMake(m *ast.Make) error {
-	if err := m.Length.Accept(g); err != nil {
-		return err
+// unwindLoop discards everything pushed since the start of the innermost loop
+// body. The code following a break or continue is unreachable, so the tracked
+// stack depth is left as is.
+func (g *Generator) unwindLoop() loopContext {
+	loop := g.ctx.loops[len(g.ctx.loops)-1]
+	if g.ctx.stackDepth > loop.stackDepth {
+		g.writefln("add $%d, %%rsp", g.ctx.stackDepth-loop.stackDepth)
 	}
-	// save length
-	g.pushIntReg("%rax")
-	// multiply by element size
-	g.writefln("imul $%d, %%rax", m.Type.Size())
-	// call malloc
-	g.writeln("mov %rax, %rdi")
+	return loop
+}
 
-	misalignment := g.ctx.stackDepth % 16
-	if misalignment != 0 {
-		g.adjustStack(16 - misalignment)
+// VisitBreak exits the innermost loop, the loop then evaluates to zero.
+func (g *Generator) VisitBreak(b *ast.Break) error {
+	g.writeln("# break")
+	loop := g.unwindLoop()
+	g.zeroLoopValue(loop.valueType)
+	g.writefln("jmp %s", loop.breakLabel)
+	return nil
+}
+
+// VisitContinue jumps back to the condition of the innermost loop.
+func (g *Generator) VisitContinue(c *ast.Continue) error {
+	g.writeln("# continue")
+	loop := g.unwindLoop()
+	g.writefln("jmp %s", loop.continueLabel)
+	return nil
+}
+
+// VisitConversion converts the value between int, float and bool. Floats are
+// truncated towards zero when converted to int.
+func (g *Generator) VisitConversion(c *ast.Conversion) error {
+	g.writeln("# conversion")
+	if err := c.Value.Accept(g); err != nil {
+		return err
 	}
 
-	g.writeln("call malloc@PLT")
+	from := c.Value.GetType()
+	switch {
+	case from.Equals(c.Type):
+	case c.Type.Equals(ast.BasicTypePtr(ast.String)):
+		if _, isNumber := from.(*ast.BasicType); isNumber {
+			g.generateFormat(from)
+		} else {
+			g.sliceOf(from)
+			g.terminatorCheck(c.Position) // the bytes are used as they are
+		}
+	case from.Equals(ast.BasicTypePtr(ast.String)):
+		g.generateStringBytes()
+	case c.Type.Equals(ast.BasicTypePtr(ast.Float)):
+		g.writeln("cvtsi2sd %rax, %xmm0")
+	case from.Equals(ast.BasicTypePtr(ast.Float)):
+		g.writeln("cvttsd2si %xmm0, %rax")
+	case c.Type.Equals(ast.BasicTypePtr(ast.Bool)):
+		g.writeln("cmp $0, %rax")
+		g.writeln("setne %al")
+		g.writeln("movzbq %al, %rax")
+	}
+	g.normalize(c.Type) // wrap around to the range of sized integers
+	return nil
+}
 
-	if misalignment != 0 {
-		g.adjustStack(-(16 - misalignment))
+// VisitLength loads the length of an array, slice or string to %rax. The
+// length of an array is known at compile time, so the array itself is not
+// evaluated, while the bytes of a string are counted.
+func (g *Generator) VisitLength(l *ast.Length) error {
+	g.writeln("# length")
+	if arrayType, isArray := l.Value.GetType().(*ast.ArrayType); isArray {
+		g.writefln("mov $%d, %%rax", arrayType.Length)
+		return nil
 	}
 
-	g.popIntReg("%rbx") // length to %rbx
+	if err := l.Value.Accept(g); err != nil {
+		return err
+	}
+	if l.Value.GetType().Equals(ast.BasicTypePtr(ast.String)) {
+		g.stringLength()
+	}
+	g.writeln("mov %rbx, %rax")
 	return nil
 }
 
-func (g *Generator) VisitRelease(r *ast.Release) error {
-	offset := g.ctx.locals[r.Value.Resolved]
-	g.loadSlice(offset) // ptr in %rax, length in %rbx
+// VisitAppend stores the value after the last element of the slice, leaving
+// the grown slice in %rax, %rbx and %rdx. A slice which is out of capacity
+// gets twice as many elements, at least 4. Its elements are reallocated when
+// the slice owns them, otherwise they are copied to a new allocation.
+func (g *Generator) VisitAppend(a *ast.Append) error {
+	g.writeln("# append")
+	sliceType := a.GetType().(*ast.SliceType)
+	size := sliceType.Element.Size()
+
+	if err := a.Slice.Accept(g); err != nil {
+		return err
+	}
+	g.sliceOf(a.Slice.GetType())
+	g.pushValue(sliceType)
+
+	if err := a.Value.Accept(g); err != nil {
+		return err
+	}
+	if _, isSlice := sliceType.Element.(*ast.SliceType); isSlice {
+		g.sliceOf(a.Value.GetType())
+	}
+	g.pushValue(sliceType.Element)
+
+	// the slice is below the value on the stack
+	base := 8
+	if _, isSlice := sliceType.Element.(*ast.SliceType); isSlice {
+		base = 24
+	}
+	storeLabel := g.label()
+	copyLabel := g.label()
+	grownLabel := g.label()
+
+	g.writefln("mov %d(%%rsp), %%rax", base+8) // length
+	g.writefln("cmp %d(%%rsp), %%rax", base+16)
+	g.writefln("jb %s", storeLabel)
+
+	g.writeln("# grow")
+	g.writeln("lea (%rax, %rax), %rsi")
+	g.writeln("mov $4, %rax")
+	g.writeln("cmp %rax, %rsi")
+	g.writeln("cmovl %rax, %rsi") // new capacity
+	g.writefln("cmpq $0, %d(%%rsp)", base+16)
+	g.writefln("mov %%rsi, %d(%%rsp)", base+16)
+	g.writefln("je %s", copyLabel)
+	g.writefln("imul $%d, %%rsi", size)
+	g.writefln("mov %d(%%rsp), %%rdi", base)
+	g.callAligned("realloc")
+	g.writefln("jmp %s", grownLabel)
+
+	g.writefln("%s:", copyLabel) // the elements are owned by something else
+	g.writefln("imul $%d, %%rsi", size)
+	g.writeln("mov %rsi, %rdi")
+	g.callAligned("malloc")
 	g.writeln("mov %rax, %rdi")
+	g.writefln("mov %d(%%rsp), %%rsi", base)
+	g.writefln("mov %d(%%rsp), %%rdx", base+8)
+	g.writefln("imul $%d, %%rdx", size)
+	g.callAligned("memcpy")
+
+	g.writefln("%s:", grownLabel)
+	g.writefln("mov %%rax, %d(%%rsp)", base)
+
+	g.writefln("%s:", storeLabel)
+	g.popValue(sliceType.Element)
+	g.writeln("mov (%rsp), %rcx")  // pointer
+	g.writeln("mov 8(%rsp), %rsi") // length
+	switch size {
+	case 1, 2, 4, 8:
+		g.writefln("lea (%%rcx, %%rsi, %d), %%rcx", size)
+	default:
+		g.writefln("imul $%d, %%rsi", size)
+		g.writeln("lea (%rcx, %rsi), %rcx")
+	}
+	g.storeIndirect(sliceType.Element)
 
-	misalignment := g.ctx.stackDepth % 16
-	if misalignment != 0 {
-		g.adjustStack(16 - misalignment)
+	g.popValue(sliceType)
+	g.writeln("inc %rbx")
+	return nil
+}
+
+// VisitCopy copies as many elements as fit from the source to the destination
+// and leaves their count in %rax. The elements may overlap.
+func (g *Generator) VisitCopy(c *ast.Copy) error {
+	g.writeln("# copy")
+	element, _ := elementType(c.Destination.GetType())
+
+	if err := c.Destination.Accept(g); err != nil {
+		return err
 	}
+	g.pushIntReg("%rbx") // length
+	g.pushIntReg("%rax") // pointer
 
-	g.writeln("call free@PLT")
+	if err := c.Source.Accept(g); err != nil {
+		return err
+	}
+	g.writeln("mov %rax, %rsi")
+	g.popIntReg("%rdi")
+	g.popIntReg("%rcx")
+	g.writeln("mov %rbx, %rdx")
+	g.writeln("cmp %rcx, %rdx")
+	g.writeln("cmovg %rcx, %rdx") // count
+	g.pushIntReg("%rdx")
+	g.writefln("imul $%d, %%rdx", element.Size())
+	g.callAligned("memmove")
+	g.popIntReg("%rax")
+	return nil
+}
 
-	if misalignment != 0 {
-		g.adjustStack(-(16 - misalignment))
+// elementType returns the element type of an array or slice type.
+func elementType(t ast.Type) (ast.Type, bool) {
+	switch t := t.(type) {
+	case *ast.ArrayType:
+		return t.Element, true
+	case *ast.SliceType:
+		return t.Element, true
 	}
+	return nil, false
+}
+
+func (g *Generator) VisitMake(m *ast.Make) error {
+	if m.Length == nil {
+		// a single value, the type is a pointer to it
+		pointer := m.Type.(*ast.PointerType)
+		g.writefln("mov $%d, %%rdi", pointer.Inner.Size())
+		g.callAligned("malloc")
+		return nil
+	}
+
+	if err := m.Length.Accept(g); err != nil {
+		return err
+	}
+	// save length
+	g.pushIntReg("%rax")
+	// multiply by element size
+	g.writefln("imul $%d, %%rax", m.Type.Size())
+	// call malloc
+	g.writeln("mov %rax, %rdi")
+	g.callAligned("malloc")
 
+	g.popIntReg("%rbx")         // length to %rbx
+	g.writeln("mov %rbx, %rdx") // the slice owns all of the allocated elements
+	return nil
+}
+
+func (g *Generator) VisitRelease(r *ast.Release) error {
+	// slices and pointers both keep the address in %rax
+	if err := r.Value.Accept(g); err != nil {
+		return err
+	}
+	g.writeln("mov %rax, %rdi")
+	g.callAligned("free")
 	return nil
 }
 
@@ -1081,17 +2104,15 @@ func (g *Generator) generateArrayLiteralInit(a *ast.ArrayLiteral, baseOffset int
 	if len(a.Values) == 0 {
 		return generatorError(a.GetPosition(), "unexpected empty array literal ")
 	}
-	elementSize := a.Values[0].GetType().Size()
-	moveInstruction := "mov %rax"
-	if a.Values[0].GetType().Equals(ast.BasicTypePtr(ast.Float)) {
-		moveInstruction = "movq %xmm0"
-	}
+	elementType := a.Values[0].GetType()
+	elementSize := elementType.Size()
 
 	for i, val := range a.Values {
 		if err := val.Accept(g); err != nil {
 			return err
 		}
-		g.writefln("%s, -%d(%%rbp)", moveInstruction, baseOffset-(i*elementSize))
+		g.writefln("lea -%d(%%rbp), %%rcx", baseOffset-(i*elementSize))
+		g.storeIndirect(elementType)
 	}
 	return nil
 }
@@ -1111,21 +2132,245 @@ func (g *Generator) VisitArrayLiteral(a *ast.ArrayLiteral) error {
 	return nil
 }
 
-// VisitIndex generates an indexed load, leaving the element value in %rax or %xmm0.
-func (g *Generator) VisitIndex(i *ast.Index) error {
-	g.writeln("# index")
+// generateIndexAddress evaluates the address of the indexed element to %rax.
+func (g *Generator) generateIndexAddress(i *ast.Index) error {
 	if err := i.Index.Accept(g); err != nil {
 		return err
 	}
 	g.pushIntReg("%rax")
-	if err := g.containerLoad(i.Identifier); err != nil {
+	if err := g.containerLoad(i.Value); err != nil {
 		return err
 	}
 	g.popIntReg("%rdx")
-	if i.GetType().Equals(ast.BasicTypePtr(ast.Float)) {
-		g.writefln("movsd (%%rcx, %%rdx, %d), %%xmm0", i.GetType().Size())
+	g.boundsCheck(i.Position)
+
+	switch size := i.GetType().Size(); size {
+	case 1, 2, 4, 8:
+		g.writefln("lea (%%rcx, %%rdx, %d), %%rax", size)
+	default:
+		g.writefln("imul $%d, %%rdx", size)
+		g.writeln("lea (%rcx, %rdx), %rax")
+	}
+	return nil
+}
+
+// VisitIndex generates an indexed load, leaving the element value in %rax or
+// %xmm0. Elements which are arrays or structs are left in place and
+// represented by their address.
+func (g *Generator) VisitIndex(i *ast.Index) error {
+	g.writeln("# index")
+	if err := g.generateIndexAddress(i); err != nil {
+		return err
+	}
+	g.loadIndirect(i.GetType())
+	return nil
+}
+
+// VisitSlice generates a slice of an array or slice, leaving the pointer to
+// the first element in %rax, the length in %rbx and a capacity of 0 in %rdx.
+// The elements are shared with the sliced value, which keeps owning them.
+func (g *Generator) VisitSlice(s *ast.Slice) error {
+	g.writeln("# slice")
+	if err := s.Value.Accept(g); err != nil {
+		return err
+	}
+	g.pushIntReg("%rax") // pointer
+	g.pushIntReg("%rbx") // length
+
+	if s.Low != nil {
+		if err := s.Low.Accept(g); err != nil {
+			return err
+		}
 	} else {
-		g.writefln("mov (%%rcx, %%rdx, %d), %%rax", i.GetType().Size())
+		g.writeln("mov $0, %rax")
 	}
+	g.pushIntReg("%rax")
+
+	if s.High != nil {
+		if err := s.High.Accept(g); err != nil {
+			return err
+		}
+		g.writeln("mov %rax, %rdx")
+	}
+	g.popIntReg("%rsi")
+	g.popIntReg("%rbx")
+	g.popIntReg("%rcx")
+	if s.High == nil {
+		g.writeln("mov %rbx, %rdx")
+	}
+	g.sliceBoundsCheck(s.Position)
+
+	g.writeln("mov %rdx, %rbx")
+	g.writeln("sub %rsi, %rbx") // length
+	switch size := s.GetType().(*ast.SliceType).Element.Size(); size {
+	case 1, 2, 4, 8:
+		g.writefln("lea (%%rcx, %%rsi, %d), %%rax", size)
+	default:
+		g.writefln("imul $%d, %%rsi", size)
+		g.writeln("lea (%rcx, %rsi), %rax")
+	}
+	g.writeln("mov $0, %rdx") // capacity
 	return nil
 }
+
+// generateAddress evaluates the address of an assignable value to %rax.
+// Values of struct type are always represented by their address, so any
+// struct value is addressable.
+func (g *Generator) generateAddress(v ast.Value) error {
+	switch v := v.(type) {
+	case *ast.Identifier:
+		if label, isGlobal := g.globals[v.Resolved]; isGlobal {
+			g.writefln("lea %s(%%rip), %%rax", label)
+			return nil
+		}
+		offset, exists := g.ctx.locals[v.Resolved]
+		if !exists {
+			return generatorError(v.Position, "unresolved identifier %q", v.Name)
+		}
+		if _, isArray := v.Resolved.GetType().(*ast.ArrayType); isArray && g.isArgument(v.Resolved) {
+			g.loadScalar(offset) // stored as a pointer when passed in
+		} else {
+			g.loadArrayAddr(offset)
+		}
+		return nil
+	case *ast.FieldAccess:
+		if err := g.generateAddress(v.Value); err != nil {
+			return err
+		}
+		structType := v.Value.GetType().(*ast.StructType)
+		_, fieldOffset := structType.Declaration.Field(v.Field.Name)
+		if fieldOffset != 0 {
+			g.writefln("add $%d, %%rax", fieldOffset)
+		}
+		return nil
+	case *ast.Dereference:
+		return v.Value.Accept(g) // pointer to %rax
+	case *ast.Index:
+		return g.generateIndexAddress(v)
+	}
+
+	if _, isStruct := v.GetType().(*ast.StructType); isStruct {
+		return v.Accept(g)
+	}
+	return generatorError(v.GetPosition(), "can't take address of value")
+}
+
+// VisitFieldAccess loads the field from the address of the accessed struct.
+func (g *Generator) VisitFieldAccess(f *ast.FieldAccess) error {
+	g.writefln("# field access (%s)", f.Field.Name)
+	if err := g.generateAddress(f); err != nil {
+		return err
+	}
+	g.loadIndirect(f.GetType())
+	return nil
+}
+
+// VisitVariantAccess loads the number of the variant to %rax.
+func (g *Generator) VisitVariantAccess(v *ast.VariantAccess) error {
+	variant := v.GetType().(*ast.EnumType).Declaration.Variant(v.Variant.Name)
+	g.writefln("# variant (%s::%s)", v.Enum.Name, v.Variant.Name)
+	g.writefln("mov $%d, %%rax", variant.Number)
+	return nil
+}
+
+// jumpTableMinCases is the least number of cases dispatched through a jump
+// table, fewer cases are compared one by one.
+const jumpTableMinCases = 4
+
+// VisitMatch jumps to the arm matching the value in %rax. Dense cases, which
+// make up at least half of the range between the smallest and the largest
+// one, are dispatched through a jump table, the others are compared in order.
+func (g *Generator) VisitMatch(m *ast.Match) error {
+	g.writeln("# match")
+	if err := m.Value.Accept(g); err != nil {
+		return err
+	}
+
+	endLabel := g.label()
+	defaultLabel := endLabel
+	if m.Else != nil {
+		defaultLabel = g.label()
+	}
+
+	armLabels := make([]string, len(m.Arms))
+	targets := map[int64]string{}
+	for i, arm := range m.Arms {
+		armLabels[i] = g.label()
+		for _, c := range arm.Cases {
+			if _, exists := targets[c]; !exists {
+				targets[c] = armLabels[i]
+			}
+		}
+	}
+
+	low, high := caseRange(targets)
+	if span := uint64(high) - uint64(low); len(targets) >= jumpTableMinCases && span < 2*uint64(len(targets)) {
+		g.generateJumpTable(targets, low, high, defaultLabel)
+	} else {
+		for i, arm := range m.Arms {
+			for _, c := range arm.Cases {
+				g.writefln("mov $%d, %%rbx", c)
+				g.writeln("cmp %rbx, %rax")
+				g.writefln("je %s", armLabels[i])
+			}
+		}
+		g.writefln("jmp %s", defaultLabel)
+	}
+
+	for i, arm := range m.Arms {
+		g.writefln("%s:", armLabels[i])
+		if err := arm.Body.Accept(g); err != nil {
+			return err
+		}
+		g.writefln("jmp %s", endLabel)
+	}
+	if m.Else != nil {
+		g.writefln("%s:", defaultLabel)
+		if err := m.Else.Accept(g); err != nil {
+			return err
+		}
+	}
+	g.writefln("%s:", endLabel)
+	return nil
+}
+
+// caseRange returns the smallest and the largest of the cases.
+func caseRange(targets map[int64]string) (low, high int64) {
+	first := true
+	for c := range targets {
+		if first || c < low {
+			low = c
+		}
+		if first || c > high {
+			high = c
+		}
+		first = false
+	}
+	return low, high
+}
+
+// generateJumpTable jumps to the target of the value in %rax through a table
+// of addresses indexed by the value minus the smallest case. The index is
+// compared unsigned, so values below the smallest case wrap around and jump
+// to the default label like the values above the largest one.
+func (g *Generator) generateJumpTable(targets map[int64]string, low, high int64, defaultLabel string) {
+	tableLabel := g.label()
+	g.writeln("# jump table")
+	g.writefln("mov $%d, %%rbx", low)
+	g.writeln("sub %rbx, %rax")
+	g.writefln("cmp $%d, %%rax", uint64(high)-uint64(low))
+	g.writefln("ja %s", defaultLabel)
+	g.writefln("lea %s(%%rip), %%rbx", tableLabel)
+	g.writeln("jmp *(%rbx, %rax, 8)")
+	g.writefln("%s:", tableLabel)
+	for c := low; ; c++ {
+		target, ok := targets[c]
+		if !ok {
+			target = defaultLabel
+		}
+		g.writefln(".quad %s", target)
+		if c == high {
+			break
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package code_generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/MisustinIvan/ilang/internal/lexer"
+	"github.com/MisustinIvan/ilang/internal/name_resolver"
+	"github.com/MisustinIvan/ilang/internal/parser"
+	"github.com/MisustinIvan/ilang/internal/type_checker"
+	"github.com/MisustinIvan/ilang/internal/type_resolver"
+)
+
+// run compiles the input with bounds checks enabled, runs it and returns its
+// output and exit error.
+func run(t *testing.T, input string) (string, error) {
+	t.Helper()
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not available")
+	}
+
+	tokens, err := lexer.New(lexer.NewSourceFile("test", input)).Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+	program, err := parser.New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parsing failed: %v", err)
+	}
+	if program, err = name_resolver.NewResolver(program).ResolveNames(); err != nil {
+		t.Fatalf("Name resolution failed: %v", err)
+	}
+	if program, err = type_resolver.NewResolver(program).ResolveTypes(); err != nil {
+		t.Fatalf("Type resolution failed: %v", err)
+	}
+	if program, err = type_checker.NewChecker(program).CheckTypes(); err != nil {
+		t.Fatalf("Type checking failed: %v", err)
+	}
+
+	generator := New(program)
+	generator.BoundsChecks = true
+	assembly, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generating failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	asmFile := filepath.Join(dir, "test.s")
+	if err := os.WriteFile(asmFile, []byte(assembly), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	binary := filepath.Join(dir, "test")
+	if out, err := exec.Command("gcc", "-no-pie", "-o", binary, asmFile, "-lm").CombinedOutput(); err != nil {
+		t.Fatalf("gcc failed: %v\n%s", err, out)
+	}
+
+	out, err := exec.Command(binary).Output()
+	return string(out), err
+}
+
+func TestStringBytesRoundTrip(t *testing.T) {
+	input := `extrn int puts(string s)
+int main() {
+	let b: []u8 = []u8("hello");
+	puts(string(b));
+	b[0] = u8('j');
+	puts(string(b));
+	puts("hello");
+	release(b);
+	0
+}`
+	out, err := run(t, input)
+	if err != nil {
+		t.Fatalf("Running failed: %v", err)
+	}
+	if expected := "hello\njello\nhello\n"; out != expected {
+		t.Fatalf("Expected output %q, got %q", expected, out)
+	}
+}
+
+func TestMissingTerminator(t *testing.T) {
+	input := `extrn int puts(string s)
+int main() {
+	let b: [2]u8 = ['n', 'o'];
+	puts(string(b));
+	0
+}`
+	if out, err := run(t, input); err == nil {
+		t.Fatalf("Expected the missing terminator to abort, got output %q", out)
+	}
+}
+
+func TestIndirectCall(t *testing.T) {
+	input := `extrn unit printf(string format, ...)
+int sum7(int a, int b, int c, int d, int e, int f, int g) { a + b + c + d + e + f + g * 100 }
+int sum8(int a, int b, int c, int d, int e, int f, int g, int h) { a + b + c + d + e + f + g + h * 100 }
+fn(int, int, int, int, int, int, int, int) int pick() { sum8 }
+int main() {
+	let table: [1]fn(int, int, int, int, int, int, int) int = [sum7];
+	let i: int = 0;
+	printf("%d %d %d\n", table[i](1, 2, 3, 4, 5, 6, 7), pick()(1, 2, 3, 4, 5, 6, 7, 8), 1 + table[0](1, 1, 1, 1, 1, table[i](0, 0, 0, 0, 0, 0, 1), 1));
+	0
+}`
+	out, err := run(t, input)
+	if err != nil {
+		t.Fatalf("Running failed: %v", err)
+	}
+	if expected := "721 828 206\n"; out != expected {
+		t.Fatalf("Expected output %q, got %q", expected, out)
+	}
+}
@@ -9,25 +9,56 @@ type localFinder struct {
 	locals      map[any]int
 }
 
-func (f *localFinder) declareLocal(size int, key any) {
-	f.stackOffset += size
+// declareLocal reserves size bytes aligned to align below the locals declared
+// so far.
+func (f *localFinder) declareLocal(size, align int, key any) {
+	f.stackOffset = alignUp(f.stackOffset+size, align)
 	f.locals[key] = f.stackOffset
 }
 
+// declareValue declares a local holding a value of type t. Scalars take their
+// own size and alignment, while arrays and structs are zeroed and copied
+// eight bytes at a time, so they take whole eightbytes.
+func (f *localFinder) declareValue(t ast.Type, key any) {
+	switch t.(type) {
+	case *ast.ArrayType, *ast.StructType:
+		f.declareLocal(alignUp(t.Size(), 8), 8, key)
+	default:
+		f.declareLocal(t.Size(), t.Align(), key)
+	}
+}
+
+func alignUp(n, align int) int {
+	if n%align != 0 {
+		n += align - n%align
+	}
+	return n
+}
+
 func (f *localFinder) VisitProgram(p *ast.Program) error                         { return nil }
 func (f *localFinder) VisitExternalDeclaration(d *ast.ExternalDeclaration) error { return nil }
+func (f *localFinder) VisitStructDeclaration(d *ast.StructDeclaration) error     { return nil }
+func (f *localFinder) VisitEnumDeclaration(d *ast.EnumDeclaration) error         { return nil }
+func (f *localFinder) VisitGlobal(g *ast.Global) error                           { return nil }
+func (f *localFinder) VisitConstant(c *ast.Constant) error                       { return nil }
+func (f *localFinder) VisitStructType(t *ast.StructType) error                   { return nil }
+func (f *localFinder) VisitEnumType(t *ast.EnumType) error                       { return nil }
+func (f *localFinder) VisitFunctionType(t *ast.FunctionType) error               { return nil }
 func (f *localFinder) VisitBasicType(t *ast.BasicType) error                     { return nil }
 func (f *localFinder) VisitArrayType(t *ast.ArrayType) error                     { return nil }
 func (f *localFinder) VisitPointerType(t *ast.PointerType) error                 { return nil }
 func (f *localFinder) VisitSliceType(t *ast.SliceType) error {
 	if t.LengthIdentifier != nil {
-		f.declareLocal(8, t.LengthIdentifier)
+		f.declareLocal(8, 8, t.LengthIdentifier)
 	}
 	return nil
 }
 func (f *localFinder) VisitLiteral(l *ast.Literal) error       { return nil }
 func (f *localFinder) VisitIdentifier(i *ast.Identifier) error { return nil }
 func (f *localFinder) VisitDeclaration(d *ast.Declaration) error {
+	if returnedThroughPointer(d.Type) {
+		f.declareLocal(8, 8, d) // hidden pointer to the return value
+	}
 	for _, arg := range d.Args {
 		_ = arg.Accept(f)
 	}
@@ -36,13 +67,12 @@ func (f *localFinder) VisitDeclaration(d *ast.Declaration) error {
 }
 func (f *localFinder) VisitArgument(a *ast.Argument) error {
 	_ = a.Type.Accept(f)
-	size := a.Type.Size()
-	_, isArray := a.Type.(*ast.ArrayType)
-	_, isSlice := a.Type.(*ast.SliceType)
-	if isArray || isSlice {
+	// arguments are spilled from whole registers or stack slots
+	size := alignUp(a.Type.Size(), 8)
+	if _, isArray := a.Type.(*ast.ArrayType); isArray {
 		size = 16 // (pointer, length)
 	}
-	f.declareLocal(size, a.Identifier)
+	f.declareLocal(size, 8, a.Identifier)
 	return nil
 }
 func (f *localFinder) VisitReturn(r *ast.Return) error {
@@ -51,16 +81,23 @@ func (f *localFinder) VisitReturn(r *ast.Return) error {
 	}
 	return nil
 }
+func (f *localFinder) VisitBreak(b *ast.Break) error       { return nil }
+func (f *localFinder) VisitContinue(c *ast.Continue) error { return nil }
 func (f *localFinder) VisitBind(b *ast.Bind) error {
 	_ = b.Type.Accept(f) // handles slice LengthIdentifier
-	f.declareLocal(b.Type.Size(), b.Identifier)
+	f.declareValue(b.Type, b.Identifier)
 	_ = b.Value.Accept(f)
 	return nil
 }
 func (f *localFinder) VisitCall(c *ast.Call) error {
+	_ = c.Function.Accept(f)
 	for _, arg := range c.Arguments {
 		_ = arg.Accept(f)
 	}
+	switch c.GetType().(type) {
+	case *ast.StructType, *ast.ArrayType:
+		f.declareValue(c.GetType(), c) // temporary for the return value
+	}
 	return nil
 }
 func (f *localFinder) VisitSeparated(s *ast.Separated) error { return s.Value.Accept(f) }
@@ -87,7 +124,18 @@ func (f *localFinder) VisitCondition(c *ast.Condition) error {
 	}
 	return nil
 }
+func (f *localFinder) VisitMatch(m *ast.Match) error {
+	_ = m.Value.Accept(f)
+	for _, arm := range m.Arms {
+		_ = arm.Body.Accept(f)
+	}
+	if m.Else != nil {
+		_ = m.Else.Accept(f)
+	}
+	return nil
+}
 func (f *localFinder) VisitAssignment(a *ast.Assignment) error {
+	_ = a.Target.Accept(f)
 	_ = a.Value.Accept(f)
 	return nil
 }
@@ -101,7 +149,27 @@ func (f *localFinder) VisitLoop(l *ast.Loop) error {
 	return nil
 }
 func (f *localFinder) VisitMake(m *ast.Make) error {
-	_ = m.Length.Accept(f)
+	if m.Length != nil {
+		_ = m.Length.Accept(f)
+	}
+	return nil
+}
+func (f *localFinder) VisitConversion(c *ast.Conversion) error {
+	_ = c.Value.Accept(f)
+	return nil
+}
+func (f *localFinder) VisitLength(l *ast.Length) error {
+	_ = l.Value.Accept(f)
+	return nil
+}
+func (f *localFinder) VisitAppend(a *ast.Append) error {
+	_ = a.Slice.Accept(f)
+	_ = a.Value.Accept(f)
+	return nil
+}
+func (f *localFinder) VisitCopy(c *ast.Copy) error {
+	_ = c.Destination.Accept(f)
+	_ = c.Source.Accept(f)
 	return nil
 }
 func (f *localFinder) VisitRelease(r *ast.Release) error {
@@ -109,19 +177,32 @@ func (f *localFinder) VisitRelease(r *ast.Release) error {
 	return nil
 }
 func (f *localFinder) VisitIndex(i *ast.Index) error {
+	_ = i.Value.Accept(f)
 	_ = i.Index.Accept(f)
 	return nil
 }
+func (f *localFinder) VisitSlice(s *ast.Slice) error {
+	_ = s.Value.Accept(f)
+	if s.Low != nil {
+		_ = s.Low.Accept(f)
+	}
+	if s.High != nil {
+		_ = s.High.Accept(f)
+	}
+	return nil
+}
 func (f *localFinder) VisitArrayLiteral(a *ast.ArrayLiteral) error {
 	for _, val := range a.Values {
 		_ = val.Accept(f)
 	}
-	f.declareLocal(a.GetType().Size(), a)
+	f.declareValue(a.GetType(), a)
 	return nil
 }
+func (f *localFinder) VisitFieldAccess(fa *ast.FieldAccess) error    { return fa.Value.Accept(f) }
+func (f *localFinder) VisitVariantAccess(v *ast.VariantAccess) error { return nil }
 
 func findLocals(d *ast.Declaration) (map[any]int, int) {
 	l := &localFinder{locals: map[any]int{}}
 	_ = d.Accept(l)
-	return l.locals, l.stackOffset
+	return l.locals, alignUp(l.stackOffset, 8)
 }
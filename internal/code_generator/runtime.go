@@ -0,0 +1,175 @@
+package code_generator
+
+import (
+	"github.com/MisustinIvan/ilang/internal/ast"
+)
+
+// generateConcat concatenates the strings into a new heap allocation, leaving
+// it in %rax. A left string which was just allocated itself is grown in place
+// instead, so chains of concatenations allocate only one string. A right
+// string which was just allocated is released once it is copied, because
+// nothing else refers to it.
+func (g *Generator) generateConcat(b *ast.Binary) error {
+	g.writeln("# string concatenation")
+	g.stringRuntime = true
+	if err := b.Left.Accept(g); err != nil {
+		return err
+	}
+	g.pushIntReg("%rax")
+	if err := b.Right.Accept(g); err != nil {
+		return err
+	}
+	g.writeln("mov %rax, %rsi")
+	g.popIntReg("%rdi")
+	if ast.IsAllocation(b.Left) {
+		g.writeln("mov $1, %rdx")
+	} else {
+		g.writeln("mov $0, %rdx")
+	}
+	if !ast.IsAllocation(b.Right) {
+		g.writeln("call .string_concat")
+		return nil
+	}
+
+	g.pushIntReg("%rsi")
+	g.writeln("call .string_concat")
+	g.popIntReg("%rdi")
+	g.pushIntReg("%rax")
+	g.callAligned("free")
+	g.popIntReg("%rax")
+	return nil
+}
+
+// generateFormat formats the number of type t in %rax or %xmm0 into a new
+// heap allocated string, leaving it in %rax.
+func (g *Generator) generateFormat(t ast.Type) {
+	g.stringRuntime = true
+	switch {
+	case t.Equals(ast.BasicTypePtr(ast.Float)):
+		g.writeln("lea .string_format_float(%rip), %rsi")
+	case ast.IsUnsigned(t):
+		g.writeln("lea .string_format_unsigned(%rip), %rsi")
+	default:
+		g.writeln("lea .string_format_signed(%rip), %rsi")
+	}
+	g.writeln("mov %rax, %rdi")
+	g.writeln("call .string_format")
+}
+
+// generateStringBytes copies the bytes of the string in %rax into a new heap
+// allocation, so that writing them doesn't change the string, leaving the
+// slice of the bytes in %rax, %rbx and %rdx. The terminating zero is copied
+// too and is owned by the slice, but it isn't counted in its length.
+func (g *Generator) generateStringBytes() {
+	g.stringLength()
+	g.pushIntReg("%rax")
+	g.pushIntReg("%rbx")
+	g.writeln("lea 1(%rbx), %rdi")
+	g.callAligned("malloc")
+	g.writeln("mov %rax, %rdi")
+	g.writeln("mov 8(%rsp), %rsi")
+	g.writeln("mov (%rsp), %rdx")
+	g.writeln("inc %rdx") // with the terminating zero
+	g.callAligned("memcpy")
+	g.popIntReg("%rbx")
+	g.popIntReg("%rcx")
+	g.writeln("lea 1(%rbx), %rdx") // capacity
+}
+
+// generateStringRuntime generates the routines building strings at runtime.
+// They align the stack on their own, so they can be called at any depth.
+func (g *Generator) generateStringRuntime() {
+	g.writeln("")
+	g.writeln("# string runtime")
+	g.writeln(".text")
+
+	// .string_concat expects the left string in %rdi, the right one in %rsi
+	// and whether the left one can be grown in place in %rdx
+	g.writeln(".string_concat:")
+	g.writeln("push %rbp")
+	g.writeln("mov %rsp, %rbp")
+	g.writeln("push %rbx")
+	g.writeln("push %r12")
+	g.writeln("push %r13")
+	g.writeln("push %r14")
+	g.writeln("push %r15")
+	g.writeln("and $-16, %rsp")
+	g.writeln("mov %rdi, %r12") // left
+	g.writeln("mov %rsi, %r13") // right
+	g.writeln("mov %rdx, %rbx") // grow in place
+	g.writeln("call strlen@PLT")
+	g.writeln("mov %rax, %r14") // left length
+	g.writeln("mov %r13, %rdi")
+	g.writeln("call strlen@PLT")
+	g.writeln("mov %rax, %r15") // right length
+	g.writeln("lea 1(%r14, %r15), %rsi")
+	g.writeln("test %rbx, %rbx")
+	g.writeln("jz .string_concat_copy")
+	g.writeln("mov %r12, %rdi")
+	g.writeln("call realloc@PLT")
+	g.writeln("jmp .string_concat_append")
+	g.writeln(".string_concat_copy:")
+	g.writeln("mov %rsi, %rdi")
+	g.writeln("call malloc@PLT")
+	g.writeln("mov %rax, %rdi")
+	g.writeln("mov %r12, %rsi")
+	g.writeln("mov %r14, %rdx")
+	g.writeln("call memcpy@PLT")
+	g.writeln(".string_concat_append:")
+	g.writeln("mov %rax, %r12")
+	g.writeln("lea (%r12, %r14), %rdi")
+	g.writeln("mov %r13, %rsi")
+	g.writeln("lea 1(%r15), %rdx") // with the terminating zero
+	g.writeln("call memcpy@PLT")
+	g.writeln("mov %r12, %rax")
+	g.writeln("lea -40(%rbp), %rsp")
+	g.writeln("pop %r15")
+	g.writeln("pop %r14")
+	g.writeln("pop %r13")
+	g.writeln("pop %r12")
+	g.writeln("pop %rbx")
+	g.writeln("pop %rbp")
+	g.writeln("ret")
+
+	// .string_format expects the format in %rsi and the number in %rdi or
+	// %xmm0, whichever the format uses
+	g.writeln(".string_format:")
+	g.writeln("push %rbp")
+	g.writeln("mov %rsp, %rbp")
+	g.writeln("push %r12")
+	g.writeln("push %r13")
+	g.writeln("and $-16, %rsp")
+	g.writeln("sub $16, %rsp")
+	g.writeln("movsd %xmm0, (%rsp)")
+	g.writeln("mov %rdi, %r12") // integer
+	g.writeln("mov %rsi, %r13") // format
+	g.writefln("mov $%d, %%rdi", formatBufferSize)
+	g.writeln("call malloc@PLT")
+	g.writeln("mov %rax, %rdi")
+	g.writefln("mov $%d, %%rsi", formatBufferSize)
+	g.writeln("mov %r13, %rdx")
+	g.writeln("mov %r12, %rcx")
+	g.writeln("movsd (%rsp), %xmm0")
+	g.writeln("mov %rax, %r12") // buffer
+	g.writeln("mov $1, %eax")
+	g.writeln("call snprintf@PLT")
+	g.writeln("mov %r12, %rax")
+	g.writeln("lea -16(%rbp), %rsp")
+	g.writeln("pop %r13")
+	g.writeln("pop %r12")
+	g.writeln("pop %rbp")
+	g.writeln("ret")
+
+	g.writeln("")
+	g.writeln(".section .rodata")
+	g.writeln(".string_format_signed:")
+	g.writeln(`.asciz "%ld"`)
+	g.writeln(".string_format_unsigned:")
+	g.writeln(`.asciz "%lu"`)
+	g.writeln(".string_format_float:")
+	g.writeln(`.asciz "%g"`)
+}
+
+// formatBufferSize is the size of the strings numbers are formatted into,
+// which fits any 64-bit integer and any float formatted by %g.
+const formatBufferSize = 32
@@ -6,10 +6,13 @@ The lexer consumes a SourceFile and emits a slice of Tokens.
 package lexer
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type SourceFile struct {
@@ -83,9 +86,13 @@ func (l *Lexer) current() byte {
 	return l.source.content[l.head]
 }
 
+// next consumes one byte. Columns count characters, so the continuation bytes
+// of multi-byte UTF-8 characters don't advance the column.
 func (l *Lexer) next() {
 	l.head++
-	l.column++
+	if !l.headInBounds() || utf8.RuneStart(l.current()) {
+		l.column++
+	}
 }
 
 func (l *Lexer) newLine() {
@@ -107,14 +114,217 @@ func isDigit(x byte) bool {
 	return x >= '0' && x <= '9'
 }
 
+func isHexDigit(x byte) bool {
+	return isDigit(x) || (x >= 'a' && x <= 'f') || (x >= 'A' && x <= 'F')
+}
+
 func isLetter(x byte) bool {
 	return (x >= 'a' && x <= 'z') || (x >= 'A' && x <= 'Z') || x == '_'
 }
 
+func isOperatorChar(x byte) bool {
+	return !isDigit(x) && !isWhitespace(x) && !isLetter(x) && !PunctuatorTokens[string(x)] && x != '.'
+}
+
 func lexError(position Position, message string, args ...any) error {
 	return fmt.Errorf("%s %s\n%s", position.String(), fmt.Sprintf(message, args...), position.Snippet(1))
 }
 
+// basePrefixes maps the second character of integer literal base prefixes to
+// the name of the base and the digits valid in it.
+var basePrefixes = map[byte]struct{ name, digits string }{
+	'x': {"hexadecimal", "0123456789abcdefABCDEF"},
+	'b': {"binary", "01"},
+	'o': {"octal", "01234567"},
+}
+
+// lexNumber lexes an integer or float literal starting under the head. Integer
+// literals can have a 0x, 0b or 0o base prefix, float literals an exponent,
+// and both can separate digits with underscores. The value is emitted as
+// written, see ParseIntLiteral and ParseFloatLiteral.
+func (l *Lexer) lexNumber() error {
+	startPos := l.currentPos()
+	start := l.head
+
+	if l.current() == '0' && l.head+1 < l.source_len {
+		if base, ok := basePrefixes[l.source.content[l.head+1]]; ok {
+			l.next()
+			l.next()
+			digitsStart := l.head
+			for l.headInBounds() && (isDigit(l.current()) || isLetter(l.current())) {
+				l.next()
+			}
+			digits := l.source.content[digitsStart:l.head]
+			if digits == "" {
+				return lexError(startPos, "%s literal has no digits", base.name)
+			}
+			for _, d := range digits {
+				if d != '_' && !strings.ContainsRune(base.digits, d) {
+					return lexError(startPos, "invalid digit %q in %s literal", d, base.name)
+				}
+			}
+			return l.emitNumber(startPos, start, isHexDigit)
+		}
+	}
+
+	for l.headInBounds() && (isDigit(l.current()) || l.current() == '.' || l.current() == '_') {
+		l.next()
+	}
+	if strings.Count(l.source.content[start:l.head], ".") > 1 {
+		return lexError(startPos, "invalid float literal")
+	}
+
+	// exponent
+	if l.current() == 'e' || l.current() == 'E' {
+		l.next()
+		if l.current() == '+' || l.current() == '-' {
+			l.next()
+		}
+		if !isDigit(l.current()) {
+			return lexError(startPos, "float literal exponent has no digits")
+		}
+		for l.headInBounds() && (isDigit(l.current()) || l.current() == '_') {
+			l.next()
+		}
+	}
+
+	return l.emitNumber(startPos, start, isDigit)
+}
+
+// emitNumber emits the number literal from start to the head, checking that
+// every underscore separates two digits.
+func (l *Lexer) emitNumber(startPos Position, start int, isDigit func(byte) bool) error {
+	value := l.source.content[start:l.head]
+	for i := range len(value) {
+		if value[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(value)-1 || !isDigit(value[i-1]) || !isDigit(value[i+1]) {
+			return lexError(startPos, "'_' must separate successive digits in number literal %s", value)
+		}
+	}
+
+	l.output = append(l.output, Token{
+		Kind:     Literal,
+		Value:    value,
+		Position: startPos,
+	})
+	return nil
+}
+
+// ParseIntLiteral parses the value of an integer literal token, which has to
+// fit into int64.
+func ParseIntLiteral(value string) (int64, error) {
+	digits, base := splitIntLiteral(value)
+	return strconv.ParseInt(digits, base, 64)
+}
+
+// ParseUnsignedLiteral parses the value of an integer literal token like
+// ParseIntLiteral, except that values above the range of int64 are accepted
+// as long as they fit into u64. Those are returned as their 64 bits, which
+// is how u64 values are stored.
+func ParseUnsignedLiteral(value string) (int64, error) {
+	if number, err := ParseIntLiteral(value); !errors.Is(err, strconv.ErrRange) {
+		return number, err
+	}
+	digits, base := splitIntLiteral(value)
+	number, err := strconv.ParseUint(digits, base, 64)
+	return int64(number), err
+}
+
+// splitIntLiteral returns the digits of an integer literal without the base
+// prefix and underscores, and the base they are written in.
+func splitIntLiteral(value string) (string, int) {
+	value = strings.ReplaceAll(value, "_", "")
+	base := 10
+	if len(value) > 2 && value[0] == '0' {
+		switch value[1] {
+		case 'x':
+			base = 16
+		case 'b':
+			base = 2
+		case 'o':
+			base = 8
+		}
+		if base != 10 {
+			value = value[2:]
+		}
+	}
+	return value, base
+}
+
+// ParseFloatLiteral parses the value of a float literal token.
+func ParseFloatLiteral(value string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64)
+}
+
+// simpleEscapes maps the characters following a backslash to the bytes they
+// stand for.
+var simpleEscapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'\\': '\\',
+	'"':  '"',
+	'\'': '\'',
+}
+
+// lexEscape decodes the escape sequence starting at the backslash under the
+// head and writes it to b. Besides the simple escapes, \xNN writes a single
+// byte and \u{...} writes a Unicode code point encoded as UTF-8.
+func (l *Lexer) lexEscape(b *strings.Builder) error {
+	startPos := l.currentPos()
+	l.next() // consume backslash
+
+	c := l.current()
+	if decoded, ok := simpleEscapes[c]; ok {
+		b.WriteByte(decoded)
+		l.next()
+		return nil
+	}
+
+	switch c {
+	case 'x':
+		l.next()
+		if l.head+2 > l.source_len {
+			return lexError(startPos, "invalid escape sequence, expected two hex digits after \\x")
+		}
+		digits := l.source.content[l.head : l.head+2]
+		value, err := strconv.ParseUint(digits, 16, 8)
+		if err != nil {
+			return lexError(startPos, "invalid escape sequence, expected two hex digits after \\x, got %q", digits)
+		}
+		b.WriteByte(byte(value))
+		l.next()
+		l.next()
+		return nil
+	case 'u':
+		l.next()
+		if l.current() != '{' {
+			return lexError(startPos, "invalid escape sequence, expected '{' after \\u")
+		}
+		l.next()
+		start := l.head
+		for l.headInBounds() && l.current() != '}' && l.current() != '"' && l.current() != '\n' {
+			l.next()
+		}
+		if l.current() != '}' {
+			return lexError(startPos, "unterminated unicode escape sequence")
+		}
+		digits := l.source.content[start:l.head]
+		value, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(value)) {
+			return lexError(startPos, "invalid unicode code point %q", digits)
+		}
+		b.WriteRune(rune(value))
+		l.next() // consume closing brace
+		return nil
+	}
+
+	return lexError(startPos, "invalid escape sequence \\%c", c)
+}
+
 func (l *Lexer) Lex() ([]Token, error) {
 	for l.headInBounds() {
 		c := l.current()
@@ -138,48 +348,87 @@ func (l *Lexer) Lex() ([]Token, error) {
 
 		// integer literals and float literals
 		if isDigit(c) {
+			if err := l.lexNumber(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// string literals, stored decoded between the quotes
+		if c == '"' {
 			startPos := l.currentPos()
-			start := l.head
-			for l.head < l.source_len && (isDigit(l.current()) || l.current() == '.') {
-				l.next()
+			l.next() // consume opening quote
+
+			var value strings.Builder
+			value.WriteByte('"')
+			for l.headInBounds() && l.current() != '"' {
+				switch l.current() {
+				case '\\':
+					if err := l.lexEscape(&value); err != nil {
+						return nil, err
+					}
+				case '\n':
+					value.WriteByte('\n')
+					l.next()
+					l.newLine()
+				default:
+					value.WriteByte(l.current())
+					l.next()
+				}
 			}
-			value := l.source.content[start:l.head]
 
-			if strings.Count(value, ".") > 1 {
-				return nil, lexError(startPos, "invalid float literal")
+			if !l.headInBounds() {
+				return nil, lexError(startPos, "unterminated string literal")
 			}
 
+			l.next() // consume closing quote
+			value.WriteByte('"')
+
 			l.output = append(l.output, Token{
 				Kind:     Literal,
-				Value:    value,
+				Value:    value.String(),
 				Position: startPos,
 			})
 			continue
 		}
 
-		// string literals
-		if c == '"' {
+		// character literals, stored as the integer value of the character
+		if c == '\'' {
 			startPos := l.currentPos()
-			start := l.head
 			l.next() // consume opening quote
 
-			for l.headInBounds() && l.current() != '"' {
-				if l.current() == '\n' {
-					l.newLine()
+			var value strings.Builder
+			switch l.current() {
+			case '\\':
+				if err := l.lexEscape(&value); err != nil {
+					return nil, err
+				}
+			case '\'', '\n', 0:
+				return nil, lexError(startPos, "empty character literal")
+			default:
+				r, size := utf8.DecodeRuneInString(l.source.content[l.head:])
+				value.WriteRune(r)
+				for range size {
+					l.next()
 				}
-				l.next()
 			}
 
-			if !l.headInBounds() {
-				return nil, lexError(startPos, "unterminated string literal")
+			switch l.current() {
+			case '\'':
+			case '\n', 0:
+				return nil, lexError(startPos, "unterminated character literal")
+			default:
+				return nil, lexError(startPos, "character literal must contain a single character")
 			}
-
 			l.next() // consume closing quote
 
-			value := l.source.content[start:l.head]
+			r, _ := utf8.DecodeRuneInString(value.String())
+			if value.Len() == 1 {
+				r = rune(value.String()[0]) // a single byte from \xNN
+			}
 			l.output = append(l.output, Token{
 				Kind:     Literal,
-				Value:    value,
+				Value:    strconv.Itoa(int(r)),
 				Position: startPos,
 			})
 			continue
@@ -224,6 +473,17 @@ func (l *Lexer) Lex() ([]Token, error) {
 			continue
 		}
 
+		if c == ':' && l.head+1 < l.source_len && l.source.content[l.head+1] == ':' {
+			l.output = append(l.output, Token{
+				Kind:     Punctuator,
+				Value:    "::",
+				Position: l.currentPos(),
+			})
+			l.next()
+			l.next()
+			continue
+		}
+
 		if PunctuatorTokens[string(c)] {
 			l.output = append(l.output, Token{
 				Kind:     Punctuator,
@@ -238,23 +498,32 @@ func (l *Lexer) Lex() ([]Token, error) {
 		{
 			startPos := l.currentPos()
 			start := l.head
+			end := start
 
-			for l.headInBounds() && !isDigit(l.current()) && !isWhitespace(l.current()) && !isLetter(l.current()) && !PunctuatorTokens[string(l.current())] && l.current() != '.' {
-				l.next()
+			for end < l.source_len && isOperatorChar(l.source.content[end]) {
+				end++
 			}
 
-			value := l.source.content[start:l.head]
+			// take the longest operator at the start of the run, so that
+			// ^^x lexes as two operators
+			length := end - start
+			for length > 0 && !OperatorTokens[l.source.content[start:start+length]] {
+				length--
+			}
 
-			if OperatorTokens[value] {
-				l.output = append(l.output, Token{
-					Kind:     Operator,
-					Value:    value,
-					Position: startPos,
-				})
-				continue
+			if length == 0 {
+				return nil, lexError(startPos, "unexpected token %q", l.source.content[start:end])
 			}
 
-			return nil, lexError(startPos, "unexpected token %q", value)
+			value := l.source.content[start : start+length]
+			for range length {
+				l.next()
+			}
+			l.output = append(l.output, Token{
+				Kind:     Operator,
+				Value:    value,
+				Position: startPos,
+			})
 		}
 	}
 
@@ -53,6 +53,62 @@ func TestLex(t *testing.T) {
 			},
 			expectedError: false,
 		},
+		{
+			name: "Integer Literal Bases",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  "0xFF 0b1010 0o755 1_000_000 0xdead_beef",
+			},
+			expected: []Token{
+				{Kind: Literal, Value: "0xFF"},
+				{Kind: Literal, Value: "0b1010"},
+				{Kind: Literal, Value: "0o755"},
+				{Kind: Literal, Value: "1_000_000"},
+				{Kind: Literal, Value: "0xdead_beef"},
+			},
+			expectedError: false,
+		},
+		{
+			name: "Float Literal Exponents",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  "1e-9 2.5E3 1_000.5 3.e+2",
+			},
+			expected: []Token{
+				{Kind: Literal, Value: "1e-9"},
+				{Kind: Literal, Value: "2.5E3"},
+				{Kind: Literal, Value: "1_000.5"},
+				{Kind: Literal, Value: "3.e+2"},
+			},
+			expectedError: false,
+		},
+		{
+			name: "Invalid Binary Digit",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  "0b102",
+			},
+			expected:      nil,
+			expectedError: true,
+		},
+		{
+			name: "Misplaced Digit Separator",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  "1__000",
+			},
+			expected:      nil,
+			expectedError: true,
+		},
+		{
+			name: "Missing Exponent Digits",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  "1e+",
+			},
+			expected:      nil,
+			expectedError: true,
+		},
 		{
 			name: "String Literal",
 			source: SourceFile{
@@ -73,11 +129,66 @@ func TestLex(t *testing.T) {
 			expected:      nil,
 			expectedError: true,
 		},
+		{
+			name: "Escape Sequences",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  `"a\tb\n\\\"\x41\u{e9}"`,
+			},
+			expected: []Token{
+				{Kind: Literal, Value: "\"a\tb\n\\\"A\u00e9\""},
+			},
+			expectedError: false,
+		},
+		{
+			name: "Invalid Escape Sequence",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  `"\q"`,
+			},
+			expected:      nil,
+			expectedError: true,
+		},
+		{
+			name: "Invalid Hex Escape Sequence",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  `"\x4"`,
+			},
+			expected:      nil,
+			expectedError: true,
+		},
+		{
+			name: "Character Literals",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  `'a' '[' '\n' '\'' '\x7f' 'é' '\u{1F600}'`,
+			},
+			expected: []Token{
+				{Kind: Literal, Value: "97"},
+				{Kind: Literal, Value: "91"},
+				{Kind: Literal, Value: "10"},
+				{Kind: Literal, Value: "39"},
+				{Kind: Literal, Value: "127"},
+				{Kind: Literal, Value: "233"},
+				{Kind: Literal, Value: "128512"},
+			},
+			expectedError: false,
+		},
+		{
+			name: "Invalid Character Literal",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  `'ab'`,
+			},
+			expected:      nil,
+			expectedError: true,
+		},
 		{
 			name: "Keywords",
 			source: SourceFile{
 				filename: "test.ilang",
-				content:  "let if else return extrn",
+				content:  "let if else return extrn struct import break continue const enum match fn",
 			},
 			expected: []Token{
 				{Kind: Keyword, Value: "let"},
@@ -85,6 +196,14 @@ func TestLex(t *testing.T) {
 				{Kind: Keyword, Value: "else"},
 				{Kind: Keyword, Value: "return"},
 				{Kind: Keyword, Value: "extrn"},
+				{Kind: Keyword, Value: "struct"},
+				{Kind: Keyword, Value: "import"},
+				{Kind: Keyword, Value: "break"},
+				{Kind: Keyword, Value: "continue"},
+				{Kind: Keyword, Value: "const"},
+				{Kind: Keyword, Value: "enum"},
+				{Kind: Keyword, Value: "match"},
+				{Kind: Keyword, Value: "fn"},
 			},
 			expectedError: false,
 		},
@@ -116,7 +235,7 @@ func TestLex(t *testing.T) {
 			name: "Punctuators",
 			source: SourceFile{
 				filename: "test.ilang",
-				content:  "(){};:,...",
+				content:  "(){};:,...::",
 			},
 			expected: []Token{
 				{Kind: Punctuator, Value: "("},
@@ -127,6 +246,7 @@ func TestLex(t *testing.T) {
 				{Kind: Punctuator, Value: ":"},
 				{Kind: Punctuator, Value: ","},
 				{Kind: Punctuator, Value: "..."},
+				{Kind: Punctuator, Value: "::"},
 			},
 			expectedError: false,
 		},
@@ -134,7 +254,7 @@ func TestLex(t *testing.T) {
 			name: "Operators",
 			source: SourceFile{
 				filename: "test.ilang",
-				content:  "= + - ! * / == != < > <= >= << >> && ||",
+				content:  "= + - ! * / == != < > <= >= << >> && || & | ^ ~ =>",
 			},
 			expected: []Token{
 				{Kind: Operator, Value: "="},
@@ -153,6 +273,35 @@ func TestLex(t *testing.T) {
 				{Kind: Operator, Value: ">>"},
 				{Kind: Operator, Value: "&&"},
 				{Kind: Operator, Value: "||"},
+				{Kind: Operator, Value: "&"},
+				{Kind: Operator, Value: "|"},
+				{Kind: Operator, Value: "^"},
+				{Kind: Operator, Value: "~"},
+				{Kind: Operator, Value: "=>"},
+			},
+			expectedError: false,
+		},
+		{
+			name: "Adjacent Operators",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  "^^x @@p a*-1 a<<-b",
+			},
+			expected: []Token{
+				{Kind: Operator, Value: "^"},
+				{Kind: Operator, Value: "^"},
+				{Kind: Identifier, Value: "x"},
+				{Kind: Operator, Value: "@"},
+				{Kind: Operator, Value: "@"},
+				{Kind: Identifier, Value: "p"},
+				{Kind: Identifier, Value: "a"},
+				{Kind: Operator, Value: "*"},
+				{Kind: Operator, Value: "-"},
+				{Kind: Literal, Value: "1"},
+				{Kind: Identifier, Value: "a"},
+				{Kind: Operator, Value: "<<"},
+				{Kind: Operator, Value: "-"},
+				{Kind: Identifier, Value: "b"},
 			},
 			expectedError: false,
 		},
@@ -186,6 +335,22 @@ int main(){
 			},
 			expectedError: false,
 		},
+		{
+			name: "Field Access",
+			source: SourceFile{
+				filename: "test.ilang",
+				content:  "a.b.c 1.5",
+			},
+			expected: []Token{
+				{Kind: Identifier, Value: "a"},
+				{Kind: Punctuator, Value: "."},
+				{Kind: Identifier, Value: "b"},
+				{Kind: Punctuator, Value: "."},
+				{Kind: Identifier, Value: "c"},
+				{Kind: Literal, Value: "1.5"},
+			},
+			expectedError: false,
+		},
 		{
 			name: "Simple Addition",
 			source: SourceFile{
@@ -226,3 +391,66 @@ int main(){
 		})
 	}
 }
+
+func TestLexColumns(t *testing.T) {
+	l := New(NewSourceFile("test.ilang", "let s = \"é\";\n'ö' x"))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	expected := []struct{ line, column int }{
+		{1, 1}, {1, 5}, {1, 7}, {1, 9}, {1, 12}, {2, 1}, {2, 5},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, token := range tokens {
+		if token.Position.Line != expected[i].line || token.Position.Column != expected[i].column {
+			t.Errorf("Token %q at %d:%d, expected %d:%d", token.Value, token.Position.Line, token.Position.Column, expected[i].line, expected[i].column)
+		}
+	}
+}
+
+func TestParseIntLiteral(t *testing.T) {
+	tests := map[string]int64{
+		"0":                     0,
+		"123":                   123,
+		"1_000_000":             1000000,
+		"0xFF":                  255,
+		"0b1010":                10,
+		"0o755":                 493,
+		"0755":                  755,
+		"0x7fff_ffff_ffff_ffff": 9223372036854775807,
+	}
+	for value, expected := range tests {
+		got, err := ParseIntLiteral(value)
+		if err != nil || got != expected {
+			t.Errorf("ParseIntLiteral(%q) = %d, %v, expected %d", value, got, err, expected)
+		}
+	}
+
+	if _, err := ParseIntLiteral("9223372036854775808"); err == nil {
+		t.Errorf("Expected an overflowing literal to fail")
+	}
+}
+
+func TestParseUnsignedLiteral(t *testing.T) {
+	tests := map[string]int64{
+		"123":                   123,
+		"-1":                    -1,
+		"9223372036854775808":   -9223372036854775808,
+		"18446744073709551615":  -1,
+		"0xffff_ffff_ffff_fff0": -16,
+	}
+	for value, expected := range tests {
+		got, err := ParseUnsignedLiteral(value)
+		if err != nil || got != expected {
+			t.Errorf("ParseUnsignedLiteral(%q) = %d, %v, expected %d", value, got, err, expected)
+		}
+	}
+
+	if _, err := ParseUnsignedLiteral("18446744073709551616"); err == nil {
+		t.Errorf("Expected an overflowing literal to fail")
+	}
+}
@@ -3,6 +3,7 @@ package lexer
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
 //go:generate stringer -type=TokenKind
@@ -30,9 +31,10 @@ func (p Position) Snippet(length int) string {
 	lineNumber := fmt.Sprint(p.Line)
 	padding := strings.Repeat(" ", len(lineNumber))
 	displayLine := strings.ReplaceAll(p.LineString, "\t", "    ")
-	col := min(p.Column-1, len(p.LineString))
-	prefix := strings.ReplaceAll(p.LineString[:col], "\t", "    ")
-	caret := strings.Repeat(" ", len(prefix)) + "\033[31m" + strings.Repeat("^", max(length, 1)) + "\033[0m"
+	line := []rune(p.LineString) // columns count characters
+	col := min(p.Column-1, len(line))
+	prefix := strings.ReplaceAll(string(line[:col]), "\t", "    ")
+	caret := strings.Repeat(" ", utf8.RuneCountInString(prefix)) + "\033[31m" + strings.Repeat("^", max(length, 1)) + "\033[0m"
 	return fmt.Sprintf("%s |\n%s | %s\n%s | %s", padding, lineNumber, displayLine, padding, caret)
 }
 
@@ -58,16 +60,32 @@ const KeywordExtrn = "extrn"
 const KeywordFor = "for"
 const KeywordMake = "make"
 const KeywordRelease = "release"
+const KeywordStruct = "struct"
+const KeywordImport = "import"
+const KeywordBreak = "break"
+const KeywordContinue = "continue"
+const KeywordConst = "const"
+const KeywordEnum = "enum"
+const KeywordMatch = "match"
+const KeywordFn = "fn"
 
 var KeywordTokens = map[string]bool{
-	KeywordLet:     true,
-	KeywordIf:      true,
-	KeywordElse:    true,
-	KeywordReturn:  true,
-	KeywordExtrn:   true,
-	KeywordFor:     true,
-	KeywordMake:    true,
-	KeywordRelease: true,
+	KeywordLet:      true,
+	KeywordIf:       true,
+	KeywordElse:     true,
+	KeywordReturn:   true,
+	KeywordExtrn:    true,
+	KeywordFor:      true,
+	KeywordMake:     true,
+	KeywordRelease:  true,
+	KeywordStruct:   true,
+	KeywordImport:   true,
+	KeywordBreak:    true,
+	KeywordContinue: true,
+	KeywordConst:    true,
+	KeywordEnum:     true,
+	KeywordMatch:    true,
+	KeywordFn:       true,
 }
 
 var PunctuatorTokens = map[string]bool{
@@ -80,7 +98,9 @@ var PunctuatorTokens = map[string]bool{
 	";":   true,
 	":":   true,
 	",":   true,
+	".":   true,
 	"...": true,
+	"::":  true,
 }
 
 var OperatorTokens = map[string]bool{
@@ -103,4 +123,8 @@ var OperatorTokens = map[string]bool{
 	">>": true,
 	"&&": true,
 	"||": true,
+	"&":  true,
+	"|":  true,
+	"~":  true,
+	"=>": true,
 }
@@ -0,0 +1,479 @@
+// Tracks the heap lifetime of values released within a function body.
+package lifetime_checker
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/MisustinIvan/ilang/internal/ast"
+	"github.com/MisustinIvan/ilang/internal/lexer"
+)
+
+func lifetimeError(position lexer.Position, length int, format string, args ...any) error {
+	return fmt.Errorf("%s %s\n%s", position.String(), fmt.Sprintf(format, args...), position.Snippet(length))
+}
+
+// status is the set of states a variable can be in at some point of the
+// function, one for each path leading there.
+type status uint8
+
+const (
+	live     status = 1 << iota // holds a value that was not released
+	released                    // the value was released
+	moved                       // the value was handed over somewhere else
+)
+
+// state maps variables to their status, a nil state means the point can't
+// be reached.
+type state map[*ast.Identifier]status
+
+// join merges the states of two paths meeting at the same point.
+func join(a, b state) state {
+	if a == nil {
+		return maps.Clone(b)
+	}
+	joined := maps.Clone(a)
+	for identifier, s := range b {
+		joined[identifier] |= s
+	}
+	return joined
+}
+
+// loopFlow collects the states of the paths leaving a loop body early.
+type loopFlow struct {
+	breaks    state
+	continues state
+}
+
+type Checker struct {
+	prog     *ast.Program
+	state    state
+	exit     state // the states of the paths leaving the function
+	loops    []*loopFlow
+	report   bool                          // false while loops are iterated to a fixed point
+	owners   map[*ast.Identifier]*ast.Bind // variables bound to a heap allocation
+	leaking  map[*ast.Identifier]bool      // owners already reported as never released
+	takes    map[*ast.Identifier][]status  // the status functions leave their arguments in
+	assigned map[*ast.Identifier]bool      // variables assigned after they were bound
+	warnings []error
+}
+
+func NewChecker(prog *ast.Program) *Checker {
+	return &Checker{
+		prog:     prog,
+		report:   true,
+		owners:   make(map[*ast.Identifier]*ast.Bind),
+		leaking:  make(map[*ast.Identifier]bool),
+		takes:    make(map[*ast.Identifier][]status),
+		assigned: make(map[*ast.Identifier]bool),
+	}
+}
+
+// CheckLifetimes reports releasing a variable twice on the same path and using
+// it after it was released on all paths. Variables bound to a heap allocation
+// that are never released and variables that may be released twice on
+// different paths are only reported as warnings.
+func (c *Checker) CheckLifetimes() (*ast.Program, error) { return c.prog, c.VisitProgram(c.prog) }
+
+// Warnings returns the warnings collected by CheckLifetimes.
+func (c *Checker) Warnings() []error { return c.warnings }
+
+// VisitProgram checks the functions in the order they are declared, so the
+// arguments every called function takes over are known, except for recursive
+// calls.
+func (c *Checker) VisitProgram(p *ast.Program) error {
+	var err error
+
+	for _, decl := range p.Declarations {
+		err = errors.Join(err, decl.Accept(c))
+	}
+
+	return err
+}
+
+func (c *Checker) VisitDeclaration(d *ast.Declaration) error {
+	c.state = state{}
+	for _, arg := range d.Args {
+		c.state[arg.Identifier] = live
+	}
+	c.exit = nil
+	err := d.Body.Accept(c)
+
+	if _, isReturn := d.Body.ImplicitReturn.(*ast.Return); !isReturn && d.Body.ImplicitReturn != nil {
+		c.move(d.Body.ImplicitReturn)
+	}
+	c.leave()
+
+	// an argument assigned in the function may be released with another
+	// value, which only tells that the passed value may be handed over
+	takes := make([]status, len(d.Args))
+	for i, arg := range d.Args {
+		takes[i] = c.exit[arg.Identifier]
+		if c.assigned[arg.Identifier] && takes[i]&(released|moved) != 0 {
+			takes[i] = moved
+		}
+	}
+	c.takes[d.Identifier] = takes
+
+	return err
+}
+
+// leave records the state of a path leaving the function and checks it for
+// leaks.
+func (c *Checker) leave() {
+	c.exit = join(c.exit, c.state)
+	c.checkLeaks()
+}
+
+// get returns the status of the variable on the current path.
+func (c *Checker) get(identifier *ast.Identifier) status {
+	return c.state[identifier]
+}
+
+// set sets the status of the variable on the current path.
+func (c *Checker) set(identifier *ast.Identifier, s status) {
+	if c.state != nil {
+		c.state[identifier] = s
+	}
+}
+
+// aliases returns the variables whose value the value may be.
+func aliases(v ast.Expression) []*ast.Identifier {
+	switch v := v.(type) {
+	case *ast.Separated:
+		return aliases(v.Value)
+	case *ast.Identifier:
+		return []*ast.Identifier{v.Resolved}
+	case *ast.Slice:
+		return aliases(v.Value)
+	case *ast.Append:
+		return aliases(v.Slice)
+	case *ast.Conversion: // a string converted from bytes shares their memory
+		if !ast.IsAllocation(v) {
+			return aliases(v.Value)
+		}
+	case *ast.Block:
+		return aliases(v.ImplicitReturn)
+	case *ast.Condition:
+		return append(aliases(v.Body), aliases(v.Else)...)
+	case *ast.Match:
+		identifiers := aliases(v.Else)
+		for _, arm := range v.Arms {
+			identifiers = append(identifiers, aliases(arm.Body)...)
+		}
+		return identifiers
+	}
+	return nil
+}
+
+// move marks the variables the value may be as handed over.
+func (c *Checker) move(v ast.Expression) {
+	for _, identifier := range aliases(v) {
+		c.set(identifier, moved)
+	}
+}
+
+// checkLeaks warns about the variables bound to a heap allocation that were
+// neither released nor handed over on any path reaching the function exit.
+func (c *Checker) checkLeaks() {
+	if !c.report {
+		return
+	}
+	for identifier, bind := range c.owners {
+		if c.get(identifier) == live && !c.leaking[identifier] {
+			c.leaking[identifier] = true
+			c.warnings = append(c.warnings, lifetimeError(bind.Identifier.Position, len(bind.Identifier.Name), "warning: %s is allocated on the heap but never released before the function returns", bind.Identifier.Name))
+		}
+	}
+}
+
+func (c *Checker) VisitExternalDeclaration(d *ast.ExternalDeclaration) error { return nil }
+func (c *Checker) VisitStructDeclaration(d *ast.StructDeclaration) error     { return nil }
+func (c *Checker) VisitEnumDeclaration(d *ast.EnumDeclaration) error         { return nil }
+func (c *Checker) VisitGlobal(g *ast.Global) error                           { return nil }
+func (c *Checker) VisitConstant(cn *ast.Constant) error                      { return nil }
+func (c *Checker) VisitArgument(a *ast.Argument) error                       { return nil }
+func (c *Checker) VisitBasicType(t *ast.BasicType) error                     { return nil }
+func (c *Checker) VisitArrayType(t *ast.ArrayType) error                     { return nil }
+func (c *Checker) VisitSliceType(t *ast.SliceType) error                     { return nil }
+func (c *Checker) VisitPointerType(t *ast.PointerType) error                 { return nil }
+func (c *Checker) VisitStructType(t *ast.StructType) error                   { return nil }
+func (c *Checker) VisitEnumType(t *ast.EnumType) error                       { return nil }
+func (c *Checker) VisitFunctionType(t *ast.FunctionType) error               { return nil }
+func (c *Checker) VisitVariantAccess(v *ast.VariantAccess) error             { return nil }
+func (c *Checker) VisitLiteral(l *ast.Literal) error                         { return nil }
+
+func (c *Checker) VisitReturn(r *ast.Return) error {
+	err := r.Value.Accept(c)
+	c.move(r.Value)
+	c.leave()
+	c.state = nil
+	return err
+}
+
+func (c *Checker) VisitBreak(b *ast.Break) error {
+	loop := c.loops[len(c.loops)-1]
+	loop.breaks = join(loop.breaks, c.state)
+	c.state = nil
+	return nil
+}
+
+func (c *Checker) VisitContinue(cn *ast.Continue) error {
+	loop := c.loops[len(c.loops)-1]
+	loop.continues = join(loop.continues, c.state)
+	c.state = nil
+	return nil
+}
+
+func (c *Checker) VisitBind(b *ast.Bind) error {
+	err := b.Value.Accept(c)
+	c.move(b.Value)
+
+	if ast.IsAllocation(b.Value) {
+		c.owners[b.Identifier] = b
+	}
+	c.set(b.Identifier, live)
+
+	return err
+}
+
+// unwrap strips the parentheses around the value.
+func unwrap(v ast.Value) ast.Value {
+	for {
+		separated, ok := v.(*ast.Separated)
+		if !ok {
+			return v
+		}
+		v = separated.Value
+	}
+}
+
+func (c *Checker) VisitIdentifier(i *ast.Identifier) error {
+	if c.get(i.Resolved) == released {
+		return lifetimeError(i.Position, len(i.Name), "use of %s after it was released", i.Name)
+	}
+	return nil
+}
+
+// VisitCall leaves the arguments which the called function releases or hands
+// over in the status the function leaves them in. Functions called through
+// pointers are assumed to leave their arguments alone.
+func (c *Checker) VisitCall(cl *ast.Call) error {
+	err := cl.Function.Accept(c)
+
+	for _, arg := range cl.Arguments {
+		err = errors.Join(err, arg.Accept(c))
+	}
+	var takes []status
+	if identifier, ok := cl.Function.(*ast.Identifier); ok {
+		takes = c.takes[identifier.Resolved]
+	}
+	for i, s := range takes {
+		if s&(released|moved) == 0 {
+			continue
+		}
+		for _, identifier := range aliases(cl.Arguments[i]) {
+			c.set(identifier, s)
+		}
+	}
+
+	return err
+}
+
+func (c *Checker) VisitSeparated(s *ast.Separated) error { return s.Value.Accept(c) }
+
+func (c *Checker) VisitUnary(u *ast.Unary) error {
+	// the variable can be changed through its address, so nothing is known
+	// about its value anymore
+	if identifier, ok := unwrap(u.Value).(*ast.Identifier); ok && u.Operator == ast.AddressOf {
+		c.set(identifier.Resolved, moved)
+		return nil
+	}
+	return u.Value.Accept(c)
+}
+
+func (c *Checker) VisitBinary(b *ast.Binary) error {
+	return errors.Join(b.Left.Accept(c), b.Right.Accept(c))
+}
+
+func (c *Checker) VisitBlock(b *ast.Block) error {
+	var err error
+
+	for _, expr := range b.Body {
+		err = errors.Join(err, expr.Accept(c))
+	}
+	if b.ImplicitReturn != nil {
+		err = errors.Join(err, b.ImplicitReturn.Accept(c))
+	}
+
+	return err
+}
+
+func (c *Checker) VisitCondition(cd *ast.Condition) error {
+	err := cd.Condition.Accept(c)
+	before := maps.Clone(c.state)
+
+	err = errors.Join(err, cd.Body.Accept(c))
+	after := c.state
+
+	c.state = before
+	if cd.Else != nil {
+		err = errors.Join(err, cd.Else.Accept(c))
+	}
+	c.state = join(after, c.state)
+
+	return err
+}
+
+// VisitMatch checks every arm from the state after the matched value. A match
+// that is not exhaustive can also be left without running any of the arms.
+func (c *Checker) VisitMatch(m *ast.Match) error {
+	err := m.Value.Accept(c)
+	before := c.state
+
+	var after state
+	for _, arm := range m.Arms {
+		c.state = maps.Clone(before)
+		err = errors.Join(err, arm.Body.Accept(c))
+		after = join(after, c.state)
+	}
+	if m.Else != nil {
+		c.state = maps.Clone(before)
+		err = errors.Join(err, m.Else.Accept(c))
+		after = join(after, c.state)
+	}
+	if !m.Exhaustive() {
+		after = join(after, before)
+	}
+	c.state = after
+
+	return err
+}
+
+func (c *Checker) VisitIndex(i *ast.Index) error {
+	return errors.Join(i.Value.Accept(c), i.Index.Accept(c))
+}
+
+func (c *Checker) VisitSlice(s *ast.Slice) error {
+	err := s.Value.Accept(c)
+	if s.Low != nil {
+		err = errors.Join(err, s.Low.Accept(c))
+	}
+	if s.High != nil {
+		err = errors.Join(err, s.High.Accept(c))
+	}
+	return err
+}
+
+func (c *Checker) VisitAssignment(a *ast.Assignment) error {
+	err := a.Value.Accept(c)
+
+	identifier, isIdentifier := unwrap(a.Target).(*ast.Identifier)
+	if !isIdentifier {
+		err = errors.Join(err, a.Target.Accept(c))
+		c.move(a.Value)
+		return err
+	}
+
+	// appending to the variable itself keeps the value in it
+	for _, alias := range aliases(a.Value) {
+		if alias != identifier.Resolved {
+			c.set(alias, moved)
+		}
+	}
+	c.set(identifier.Resolved, live)
+	c.assigned[identifier.Resolved] = true
+
+	return err
+}
+
+func (c *Checker) VisitArrayLiteral(a *ast.ArrayLiteral) error {
+	var err error
+
+	for _, value := range a.Values {
+		err = errors.Join(err, value.Accept(c))
+	}
+
+	return err
+}
+
+func (c *Checker) VisitDereference(d *ast.Dereference) error { return d.Value.Accept(c) }
+
+// VisitLoop iterates the body until the state at the start of the loop stops
+// changing, and only then reports errors from a last iteration.
+func (c *Checker) VisitLoop(l *ast.Loop) error {
+	entry := c.state
+	head := maps.Clone(entry)
+
+	report := c.report
+	c.report = false
+	for {
+		c.state = maps.Clone(head)
+		_, flow, _ := c.iterate(l)
+		next := join(entry, join(c.state, flow.continues))
+		if maps.Equal(next, head) {
+			break
+		}
+		head = next
+	}
+	c.report = report
+
+	c.state = maps.Clone(head)
+	exit, flow, err := c.iterate(l)
+	c.state = join(exit, flow.breaks)
+
+	return err
+}
+
+// iterate checks the condition and the body of the loop once, returning the
+// state in which the loop is left when the condition is false.
+func (c *Checker) iterate(l *ast.Loop) (state, *loopFlow, error) {
+	err := l.Condition.Accept(c)
+	exit := maps.Clone(c.state)
+
+	flow := &loopFlow{}
+	c.loops = append(c.loops, flow)
+	err = errors.Join(err, l.Body.Accept(c))
+	c.loops = c.loops[:len(c.loops)-1]
+
+	return exit, flow, err
+}
+
+func (c *Checker) VisitMake(m *ast.Make) error {
+	if m.Length != nil {
+		return m.Length.Accept(c)
+	}
+	return nil
+}
+
+func (c *Checker) VisitRelease(r *ast.Release) error {
+	identifier, ok := unwrap(r.Value).(*ast.Identifier)
+	if !ok {
+		return r.Value.Accept(c)
+	}
+
+	// the paths are not told apart, so releasing on exclusive paths is only
+	// reported as a warning
+	var err error
+	if s := c.get(identifier.Resolved); s == released {
+		err = lifetimeError(identifier.Position, len(identifier.Name), "%s is already released", identifier.Name)
+	} else if s&released != 0 && c.report {
+		c.warnings = append(c.warnings, lifetimeError(identifier.Position, len(identifier.Name), "warning: %s may already be released on some path", identifier.Name))
+	}
+	c.set(identifier.Resolved, released)
+
+	return err
+}
+
+func (c *Checker) VisitFieldAccess(f *ast.FieldAccess) error { return f.Value.Accept(c) }
+func (c *Checker) VisitConversion(cv *ast.Conversion) error  { return cv.Value.Accept(c) }
+func (c *Checker) VisitLength(l *ast.Length) error           { return l.Value.Accept(c) }
+
+func (c *Checker) VisitAppend(a *ast.Append) error {
+	return errors.Join(a.Slice.Accept(c), a.Value.Accept(c))
+}
+
+func (c *Checker) VisitCopy(cp *ast.Copy) error {
+	return errors.Join(cp.Destination.Accept(c), cp.Source.Accept(c))
+}
@@ -0,0 +1,171 @@
+package lifetime_checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MisustinIvan/ilang/internal/lexer"
+	"github.com/MisustinIvan/ilang/internal/name_resolver"
+	"github.com/MisustinIvan/ilang/internal/parser"
+	"github.com/MisustinIvan/ilang/internal/type_checker"
+	"github.com/MisustinIvan/ilang/internal/type_resolver"
+)
+
+// check runs the passes before the lifetime checker on the input and then
+// the lifetime checker itself, returning its warnings and error.
+func check(t *testing.T, input string) ([]error, error) {
+	t.Helper()
+	tokens, err := lexer.New(lexer.NewSourceFile("test", input)).Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+	program, err := parser.New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parsing failed: %v", err)
+	}
+	if program, err = name_resolver.NewResolver(program).ResolveNames(); err != nil {
+		t.Fatalf("Name resolution failed: %v", err)
+	}
+	if program, err = type_resolver.NewResolver(program).ResolveTypes(); err != nil {
+		t.Fatalf("Type resolution failed: %v", err)
+	}
+	if program, err = type_checker.NewChecker(program).CheckTypes(); err != nil {
+		t.Fatalf("Type checking failed: %v", err)
+	}
+
+	c := NewChecker(program)
+	_, err = c.CheckLifetimes()
+	return c.Warnings(), err
+}
+
+func TestCheckLifetimes(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedError   string // a part of the error, empty if there is none
+		expectedWarning string // a part of the only warning, empty if there is none
+	}{
+		{
+			name:  "Released",
+			input: "int main() { let s: []int = make(int, 4); s[0] = 1; release(s); 0 }",
+		},
+		{
+			name:          "ReleasedTwice",
+			input:         "int main() { let s: []int = make(int, 4); release(s); release(s); 0 }",
+			expectedError: "s is already released",
+		},
+		{
+			name:          "UsedAfterRelease",
+			input:         "int main() { let s: []int = make(int, 4); release(s); s[0] }",
+			expectedError: "use of s after it was released",
+		},
+		{
+			name:  "UsedAfterReleaseOnSomePath",
+			input: "int main() { let c: bool = true; let s: []int = make(int, 4); if c { release(s); }; let n: int = len(s); if !c { release(s); }; n }",
+			// only a use after the release on all paths is an error
+			expectedWarning: "s may already be released on some path",
+		},
+		{
+			name:            "ReleasedOnExclusivePaths",
+			input:           "int main() { let c: bool = true; let s: []int = make(int, 4); if c { release(s); }; if !c { release(s); }; 0 }",
+			expectedWarning: "s may already be released on some path",
+		},
+		{
+			name:  "ReleasedOnBothBranches",
+			input: "int main() { let c: bool = true; let s: []int = make(int, 4); if c { release(s); } else { release(s); }; 0 }",
+		},
+		{
+			name:          "ReleasedAfterBothBranches",
+			input:         "int main() { let c: bool = true; let s: []int = make(int, 4); if c { release(s); } else { release(s); }; release(s); 0 }",
+			expectedError: "s is already released",
+		},
+		{
+			name:            "NeverReleased",
+			input:           "int main() { let s: []int = make(int, 4); s[0] }",
+			expectedWarning: "s is allocated on the heap but never released",
+		},
+		{
+			name:  "ReleasedOnSomePath",
+			input: "int main() { let c: bool = true; let s: []int = make(int, 4); if c { release(s); }; 0 }",
+		},
+		{
+			name:  "Returned",
+			input: "[]int numbers() { let s: []int = make(int, 4); s } int main() { let s: []int = numbers(); release(s); 0 }",
+		},
+		{
+			name:  "Appended",
+			input: "int main() { let s: []int = make(int, 0); s = append(s, 1); release(s); 0 }",
+		},
+		{
+			name:  "PassedToReleasingFunction",
+			input: "unit free_it([]int s) { release(s); } int main() { let s: []int = make(int, 4); free_it(s); 0 }",
+		},
+		{
+			name:  "PassedThroughFunctions",
+			input: "unit free_it([]int s) { release(s); } unit pass_it([]int s) { free_it(s); } int main() { let s: []int = make(int, 4); pass_it(s); 0 }",
+		},
+		{
+			name:          "UsedAfterReleasingFunction",
+			input:         "unit free_it([]int s) { release(s); } int main() { let s: []int = make(int, 4); free_it(s); s[0] }",
+			expectedError: "use of s after it was released",
+		},
+		{
+			name:          "ReleasedAfterReleasingFunction",
+			input:         "unit free_it([]int s) { release(s); } int main() { let s: []int = make(int, 4); free_it(s); release(s); 0 }",
+			expectedError: "s is already released",
+		},
+		{
+			name:          "ReleasedAfterFunctionsReleasingIt",
+			input:         "unit free_it([]int s) { release(s); } unit pass_it([]int s) { free_it(s); } int main() { let s: []int = make(int, 4); pass_it(s); release(s); 0 }",
+			expectedError: "s is already released",
+		},
+		{
+			name:            "ReleasedAfterFunctionReleasingItOnSomePath",
+			input:           "unit free_if([]int s, bool c) { if c { release(s); }; } int main() { let s: []int = make(int, 4); free_if(s, true); release(s); 0 }",
+			expectedWarning: "s may already be released on some path",
+		},
+		{
+			name:  "ReleasedAfterFunctionReleasingAnotherValue",
+			input: "unit replace([]int s) { s = make(int, 4); release(s); } int main() { let s: []int = make(int, 4); replace(s); release(s); 0 }",
+		},
+		{
+			name:            "PassedToBorrowingFunction",
+			input:           "int first([]int s) { s[0] } int main() { let s: []int = make(int, 4); first(s) }",
+			expectedWarning: "s is allocated on the heap but never released",
+		},
+		{
+			name:          "ReleasedInLoop",
+			input:         "int main() { let s: []int = make(int, 4); let i: int = 0; for i < 2 { s[0] = i; i = i + 1; }; release(s); release(s); 0 }",
+			expectedError: "s is already released",
+		},
+		{
+			name:            "ReleasedInEveryIteration",
+			input:           "int main() { let s: []int = make(int, 4); let i: int = 0; for i < 2 { release(s); i = i + 1; }; 0 }",
+			expectedWarning: "s may already be released on some path",
+		},
+		{
+			name:  "ReboundInEveryIteration",
+			input: "int main() { let i: int = 0; for i < 2 { let s: []int = make(int, 4); release(s); i = i + 1; }; 0 }",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := check(t, tt.input)
+
+			if tt.expectedError == "" && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.expectedError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectedError)) {
+				t.Fatalf("expected error %q, got %v", tt.expectedError, err)
+			}
+
+			if tt.expectedWarning == "" && len(warnings) != 0 {
+				t.Fatalf("expected no warnings, got %v", warnings)
+			}
+			if tt.expectedWarning != "" && (len(warnings) != 1 || !strings.Contains(warnings[0].Error(), tt.expectedWarning)) {
+				t.Fatalf("expected warning %q, got %v", tt.expectedWarning, warnings)
+			}
+		})
+	}
+}
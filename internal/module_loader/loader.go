@@ -0,0 +1,146 @@
+/*
+Implements loading of programs made of multiple modules.
+
+The loader lexes and parses the root source file and recursively all the files
+it imports, merging the declarations of every module into a single
+ast.Program. Imported paths are resolved relative to the importing file,
+except for paths starting with std/, which name the standard library modules
+bundled with the compiler.
+*/
+package module_loader
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/MisustinIvan/ilang/internal/ast"
+	"github.com/MisustinIvan/ilang/internal/lexer"
+	"github.com/MisustinIvan/ilang/internal/parser"
+	"github.com/MisustinIvan/ilang/std"
+)
+
+// Extension is appended to imported paths which don't have any extension.
+const Extension = ".ilang"
+
+// StdPrefix starts the imported paths of the standard library modules.
+const StdPrefix = "std/"
+
+func loadError(position lexer.Position, length int, message string, args ...any) error {
+	return fmt.Errorf("%s %s\n%s", position.String(), fmt.Sprintf(message, args...), position.Snippet(length))
+}
+
+type Loader struct {
+	path    string
+	program *ast.Program
+	tokens  []lexer.Token
+	modules map[string]*ast.Module // already loaded modules by absolute path
+	loading map[string]bool        // modules on the current import chain
+}
+
+func New(path string) *Loader {
+	return &Loader{
+		path:    path,
+		program: &ast.Program{},
+		modules: map[string]*ast.Module{},
+		loading: map[string]bool{},
+	}
+}
+
+// Tokens returns the tokens of all the loaded modules in the order they were
+// lexed.
+func (l *Loader) Tokens() []lexer.Token {
+	return l.tokens
+}
+
+// Load loads the root module and all the modules it imports, returning the
+// merged *ast.Program.
+func (l *Loader) Load() (*ast.Program, error) {
+	if _, err := l.load(l.path, nil); err != nil {
+		return nil, err
+	}
+	return l.program, nil
+}
+
+// load loads the module at path unless it is already loaded. The imports of
+// the module are loaded first, so every module is appended to the program
+// after all the modules it depends on. imp is the import that requested the
+// module and is nil for the root module.
+func (l *Loader) load(path string, imp *ast.Import) (*ast.Module, error) {
+	bundled := imp != nil && strings.HasPrefix(imp.Path, StdPrefix)
+
+	key, err := filepath.Abs(path)
+	if bundled || err != nil {
+		key = filepath.Clean(path)
+	}
+
+	if module, ok := l.modules[key]; ok {
+		return module, nil
+	}
+	if l.loading[key] {
+		return nil, loadError(imp.Position, len(imp.Path)+2, "import cycle: module %s imports itself", path)
+	}
+
+	src, err := readModule(path, bundled)
+	if err != nil {
+		if imp != nil {
+			return nil, loadError(imp.Position, len(imp.Path)+2, "could not read module %q: %v", path, err)
+		}
+		return nil, fmt.Errorf("could not read %q: %v", path, err)
+	}
+
+	tokens, err := lexer.New(*src).Lex()
+	if err != nil {
+		return nil, fmt.Errorf("lex: %v", err)
+	}
+	l.tokens = append(l.tokens, tokens...)
+
+	parsed, err := parser.New(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+	module := parsed.Modules[0]
+	module.Path = path
+
+	l.loading[key] = true
+	for _, imported := range module.Imports {
+		importPath := imported.Path
+		if filepath.Ext(importPath) == "" {
+			importPath += Extension
+		}
+		if !filepath.IsAbs(importPath) && !strings.HasPrefix(importPath, StdPrefix) {
+			importPath = filepath.Join(filepath.Dir(path), importPath)
+		}
+
+		imported.Module, err = l.load(importPath, imported)
+		if err != nil {
+			return nil, err
+		}
+	}
+	delete(l.loading, key)
+
+	l.modules[key] = module
+	l.program.Modules = append(l.program.Modules, module)
+	l.program.Declarations = append(l.program.Declarations, module.Declarations...)
+	l.program.ExternalDeclarations = append(l.program.ExternalDeclarations, module.ExternalDeclarations...)
+	l.program.StructDeclarations = append(l.program.StructDeclarations, module.StructDeclarations...)
+	l.program.EnumDeclarations = append(l.program.EnumDeclarations, module.EnumDeclarations...)
+	l.program.Globals = append(l.program.Globals, module.Globals...)
+	l.program.Constants = append(l.program.Constants, module.Constants...)
+
+	return module, nil
+}
+
+// readModule reads the source of the module at path, which is looked up in the
+// bundled standard library instead of on the disk if bundled is set.
+func readModule(path string, bundled bool) (*lexer.SourceFile, error) {
+	if !bundled {
+		return lexer.ReadFile(path)
+	}
+	content, err := std.Modules.ReadFile(strings.TrimPrefix(path, StdPrefix))
+	if err != nil {
+		return nil, err
+	}
+	src := lexer.NewSourceFile(path, string(content))
+	return &src, nil
+}
@@ -16,14 +16,33 @@ type scope struct {
 }
 
 type Resolver struct {
-	program *ast.Program
-	scope   *scope
+	program   *ast.Program
+	scope     *scope
+	externs   map[*ast.Identifier]bool
+	constants map[*ast.Identifier]bool
+	enums     map[*ast.Identifier]bool
 }
 
 func NewResolver(p *ast.Program) *Resolver {
+	externs := map[*ast.Identifier]bool{}
+	for _, decl := range p.ExternalDeclarations {
+		externs[decl.Identifier] = true
+	}
+	constants := map[*ast.Identifier]bool{}
+	for _, constant := range p.Constants {
+		constants[constant.Identifier] = true
+	}
+	enums := map[*ast.Identifier]bool{}
+	for _, decl := range p.EnumDeclarations {
+		enums[decl.Identifier] = true
+	}
+
 	return &Resolver{
-		program: p,
-		scope:   nil,
+		program:   p,
+		scope:     nil,
+		externs:   externs,
+		constants: constants,
+		enums:     enums,
 	}
 }
 
@@ -55,6 +74,17 @@ func (r *Resolver) Declare(identifier *ast.Identifier) error {
 	return nil
 }
 
+// declareGlobal declares a top level name of a module. Unlike Declare it
+// tolerates the same identifier being imported multiple times and external
+// functions being declared by several modules, since those all refer to the
+// same symbol.
+func (r *Resolver) declareGlobal(identifier *ast.Identifier) error {
+	if val, exists := r.scope.locals[identifier.Name]; exists && (val == identifier || r.externs[val] && r.externs[identifier]) {
+		return nil
+	}
+	return r.Declare(identifier)
+}
+
 // Lookup tries to find an identifier in the scopes starting from the innermost
 // scope going upwards.
 func (r *Resolver) Lookup(id string) *ast.Identifier {
@@ -72,13 +102,53 @@ func (r *Resolver) ResolveNames() (*ast.Program, error) {
 
 // Implementing ast.Visitor interface...
 func (r *Resolver) VisitProgram(p *ast.Program) error {
+	var err error
+	for _, module := range p.Modules {
+		err = errors.Join(err, r.resolveModule(module))
+	}
+	return err
+}
+
+// resolveModule resolves the names of a module in its own global scope, which
+// starts out with the top level names of the imported modules.
+func (r *Resolver) resolveModule(m *ast.Module) error {
 	r.PushScope() // global scope
 	var err error
-	for _, decl := range p.ExternalDeclarations {
+
+	for _, imp := range m.Imports {
+		err = errors.Join(err, r.importModule(imp.Module))
+	}
+
+	// struct and enum names are declared upfront so that types can refer to
+	// each other regardless of the declaration order
+	for _, decl := range m.StructDeclarations {
+		err = errors.Join(err, r.declareGlobal(decl.Identifier))
+	}
+	for _, decl := range m.EnumDeclarations {
+		err = errors.Join(err, r.declareGlobal(decl.Identifier))
+	}
+
+	// constants come next, since they can give the length of array types
+	for _, constant := range m.Constants {
+		err = errors.Join(err, constant.Accept(r))
+	}
+
+	for _, decl := range m.EnumDeclarations {
+		err = errors.Join(err, decl.Accept(r))
+	}
+	for _, decl := range m.StructDeclarations {
 		err = errors.Join(err, decl.Accept(r))
 	}
 
-	for _, decl := range p.Declarations {
+	for _, decl := range m.ExternalDeclarations {
+		err = errors.Join(err, decl.Accept(r))
+	}
+
+	for _, global := range m.Globals {
+		err = errors.Join(err, global.Accept(r))
+	}
+
+	for _, decl := range m.Declarations {
 		err = errors.Join(err, decl.Accept(r))
 	}
 
@@ -86,12 +156,40 @@ func (r *Resolver) VisitProgram(p *ast.Program) error {
 	return err
 }
 
+// importModule declares the top level names of an imported module in the
+// current scope. Only the names declared by the module itself are imported,
+// the modules it imports are not visible through it.
+func (r *Resolver) importModule(m *ast.Module) error {
+	var err error
+	for _, constant := range m.Constants {
+		err = errors.Join(err, r.declareGlobal(constant.Identifier))
+	}
+	for _, decl := range m.StructDeclarations {
+		err = errors.Join(err, r.declareGlobal(decl.Identifier))
+	}
+	for _, decl := range m.EnumDeclarations {
+		err = errors.Join(err, r.declareGlobal(decl.Identifier))
+	}
+	for _, global := range m.Globals {
+		err = errors.Join(err, r.declareGlobal(global.Identifier))
+	}
+	for _, decl := range m.ExternalDeclarations {
+		err = errors.Join(err, r.declareGlobal(decl.Identifier))
+	}
+	for _, decl := range m.Declarations {
+		err = errors.Join(err, r.declareGlobal(decl.Identifier))
+	}
+	return err
+}
+
 func (r *Resolver) VisitDeclaration(d *ast.Declaration) error {
-	err := r.Declare(d.Identifier) // declare in global scope
-	r.PushScope()                  // function scope
+	var err error
+	d.Type, err = r.resolveType(d.Type)
+	err = errors.Join(err, r.declareGlobal(d.Identifier)) // declare in global scope
+	r.PushScope()                                         // function scope
 
-	for _, arg := range d.Args {
-		err = errors.Join(err, arg.Accept(r))
+	for i := range d.Args {
+		err = errors.Join(err, d.Args[i].Accept(r))
 	}
 
 	err = errors.Join(err, d.Body.Accept(r))
@@ -101,27 +199,86 @@ func (r *Resolver) VisitDeclaration(d *ast.Declaration) error {
 }
 func (r *Resolver) VisitExternalDeclaration(d *ast.ExternalDeclaration) error {
 	var err error
-	err = errors.Join(r.Declare(d.Identifier))
+	d.Type, err = r.resolveType(d.Type)
+	err = errors.Join(err, r.declareGlobal(d.Identifier))
 	r.PushScope() // function scope
 
-	for _, arg := range d.Args {
-		err = errors.Join(err, arg.Accept(r))
+	for i := range d.Args {
+		err = errors.Join(err, d.Args[i].Accept(r))
 	}
 
 	r.PopScope() // function scope
 	return err
 }
 
+// VisitStructDeclaration resolves the types of the fields, the struct name
+// itself is already declared by VisitProgram. The fields are declared in their
+// own scope to catch duplicate field names.
+func (r *Resolver) VisitStructDeclaration(d *ast.StructDeclaration) error {
+	var err error
+	r.PushScope() // field scope
+
+	for i, field := range d.Fields {
+		var typeErr error
+		d.Fields[i].Type, typeErr = r.resolveType(field.Type)
+		err = errors.Join(err, typeErr, r.Declare(field.Identifier))
+	}
+
+	r.PopScope() // field scope
+	return err
+}
+
+// VisitEnumDeclaration resolves the values of the variants, the enum name
+// itself is already declared by VisitProgram. The variants are declared in
+// their own scope to catch duplicate variant names, they are only referred to
+// through the enum name.
+func (r *Resolver) VisitEnumDeclaration(d *ast.EnumDeclaration) error {
+	var err error
+	r.PushScope() // variant scope
+
+	for _, variant := range d.Variants {
+		if variant.Value != nil {
+			err = errors.Join(err, variant.Value.Accept(r))
+		}
+		err = errors.Join(err, r.Declare(variant.Identifier))
+	}
+
+	r.PopScope() // variant scope
+	return err
+}
+
 func (r *Resolver) VisitArgument(a *ast.Argument) error {
-	return errors.Join(a.Type.Accept(r), r.Declare(a.Identifier))
+	var err error
+	a.Type, err = r.resolveType(a.Type)
+	return errors.Join(err, r.Declare(a.Identifier))
+}
+
+// VisitGlobal resolves the value before declaring the global, so it can't
+// refer to itself.
+func (r *Resolver) VisitGlobal(g *ast.Global) error {
+	var err error
+	g.Type, err = r.resolveType(g.Type)
+	return errors.Join(err, g.Value.Accept(r), r.declareGlobal(g.Identifier))
+}
+
+func (r *Resolver) VisitConstant(c *ast.Constant) error {
+	var err error
+	c.Type, err = r.resolveType(c.Type)
+	return errors.Join(err, c.Value.Accept(r), r.declareGlobal(c.Identifier))
 }
 
 func (r *Resolver) VisitReturn(e *ast.Return) error {
 	return e.Value.Accept(r)
 }
 
+func (r *Resolver) VisitBreak(b *ast.Break) error       { return nil }
+func (r *Resolver) VisitContinue(c *ast.Continue) error { return nil }
+
 func (r *Resolver) VisitBind(b *ast.Bind) error {
-	return errors.Join(b.Value.Accept(r), b.Type.Accept(r), r.Declare(b.Identifier))
+	err := b.Value.Accept(r)
+	var typeErr error
+	b.Type, typeErr = r.resolveType(b.Type)
+	return errors.Join(err, typeErr, r.Declare(b.Identifier))
 }
 
 func (r *Resolver) VisitIdentifier(i *ast.Identifier) error {
@@ -136,7 +293,7 @@ func (r *Resolver) VisitIdentifier(i *ast.Identifier) error {
 func (r *Resolver) VisitCall(c *ast.Call) error {
 	var err error
 
-	err = errors.Join(err, c.Identifier.Accept(r))
+	err = errors.Join(err, c.Function.Accept(r))
 	for _, arg := range c.Arguments {
 		err = errors.Join(err, arg.Accept(r))
 	}
@@ -197,7 +354,33 @@ func (r *Resolver) VisitLoop(l *ast.Loop) error {
 }
 
 func (r *Resolver) VisitMake(m *ast.Make) error {
-	return m.Length.Accept(r)
+	var err error
+	m.Type, err = r.resolveType(m.Type)
+	if sliceType, ok := m.Type.(*ast.SliceType); ok && sliceType.LengthIdentifier != nil {
+		err = errors.Join(err, lengthBindingError(sliceType, "can't bind a slice length in a make expression"))
+	}
+	if m.Length != nil {
+		err = errors.Join(err, m.Length.Accept(r))
+	}
+	return err
+}
+
+func (r *Resolver) VisitConversion(c *ast.Conversion) error {
+	var err error
+	c.Type, err = r.resolveType(c.Type)
+	return errors.Join(err, c.Value.Accept(r))
+}
+
+func (r *Resolver) VisitLength(l *ast.Length) error {
+	return l.Value.Accept(r)
+}
+
+func (r *Resolver) VisitAppend(a *ast.Append) error {
+	return errors.Join(a.Slice.Accept(r), a.Value.Accept(r))
+}
+
+func (r *Resolver) VisitCopy(c *ast.Copy) error {
+	return errors.Join(c.Destination.Accept(r), c.Source.Accept(r))
 }
 
 func (r *Resolver) VisitRelease(s *ast.Release) error {
@@ -205,7 +388,52 @@ func (r *Resolver) VisitRelease(s *ast.Release) error {
 }
 
 func (r *Resolver) VisitIndex(i *ast.Index) error {
-	return errors.Join(i.Identifier.Accept(r), i.Index.Accept(r))
+	return errors.Join(i.Value.Accept(r), i.Index.Accept(r))
+}
+
+func (r *Resolver) VisitSlice(s *ast.Slice) error {
+	err := s.Value.Accept(r)
+	if s.Low != nil {
+		err = errors.Join(err, s.Low.Accept(r))
+	}
+	if s.High != nil {
+		err = errors.Join(err, s.High.Accept(r))
+	}
+	return err
+}
+
+// VisitVariantAccess only resolves the enum, the variant name is resolved by
+// the type resolver like the fields of structs.
+func (r *Resolver) VisitVariantAccess(v *ast.VariantAccess) error {
+	if err := v.Enum.Accept(r); err != nil {
+		return err
+	}
+	if !r.enums[v.Enum.Resolved] {
+		return fmt.Errorf("%s %s is not an enum\n%s", v.Enum.Position.String(), v.Enum.Name, v.Enum.Position.Snippet(len(v.Enum.Name)))
+	}
+	return nil
+}
+
+func (r *Resolver) VisitMatch(m *ast.Match) error {
+	err := m.Value.Accept(r)
+
+	for _, arm := range m.Arms {
+		for _, pattern := range arm.Patterns {
+			err = errors.Join(err, pattern.Accept(r))
+		}
+		err = errors.Join(err, arm.Body.Accept(r))
+	}
+	if m.Else != nil {
+		err = errors.Join(err, m.Else.Accept(r))
+	}
+
+	return err
+}
+
+// VisitFieldAccess only resolves the accessed value, the field name is
+// resolved by the type resolver once the type of the value is known.
+func (r *Resolver) VisitFieldAccess(f *ast.FieldAccess) error {
+	return f.Value.Accept(r)
 }
 
 func (r *Resolver) VisitArrayLiteral(a *ast.ArrayLiteral) error {
@@ -216,13 +444,77 @@ func (r *Resolver) VisitArrayLiteral(a *ast.ArrayLiteral) error {
 	return err
 }
 
-func (r *Resolver) VisitBasicType(t *ast.BasicType) error     { return nil }
-func (r *Resolver) VisitArrayType(t *ast.ArrayType) error     { return nil }
-func (r *Resolver) VisitPointerType(t *ast.PointerType) error { return nil }
+func (r *Resolver) VisitBasicType(t *ast.BasicType) error   { return nil }
+func (r *Resolver) VisitStructType(t *ast.StructType) error { return t.Identifier.Accept(r) }
+func (r *Resolver) VisitEnumType(t *ast.EnumType) error     { return t.Identifier.Accept(r) }
+
+func (r *Resolver) VisitArrayType(t *ast.ArrayType) error {
+	var err error
+	t.Element, err = r.resolveElementType(t.Element)
+	return err
+}
+
+func (r *Resolver) VisitPointerType(t *ast.PointerType) error {
+	var err error
+	t.Inner, err = r.resolveElementType(t.Inner)
+	return err
+}
+
+func (r *Resolver) VisitFunctionType(t *ast.FunctionType) error {
+	var err error
+	for i, parameter := range t.Parameters {
+		var parameterErr error
+		t.Parameters[i], parameterErr = r.resolveElementType(parameter)
+		err = errors.Join(err, parameterErr)
+	}
+	var returnErr error
+	t.Return, returnErr = r.resolveElementType(t.Return)
+	return errors.Join(err, returnErr)
+}
+
 func (r *Resolver) VisitSliceType(t *ast.SliceType) error {
+	var err error
+	t.Element, err = r.resolveElementType(t.Element)
 	if t.LengthIdentifier != nil {
-		return r.Declare(t.LengthIdentifier)
+		err = errors.Join(err, r.Declare(t.LengthIdentifier))
 	}
-	return nil
+	return err
+}
+
+// resolveType resolves the names in the type. A slice type whose length
+// identifier names a constant is really an array type of that length and a
+// struct type naming an enum is really an enum type, so the returned type
+// replaces the resolved one.
+func (r *Resolver) resolveType(t ast.Type) (ast.Type, error) {
+	if structType, ok := t.(*ast.StructType); ok {
+		if ref := r.Lookup(structType.Identifier.Name); ref != nil && r.enums[ref] {
+			t = &ast.EnumType{Identifier: structType.Identifier}
+		}
+	}
+	if sliceType, ok := t.(*ast.SliceType); ok && sliceType.LengthIdentifier != nil {
+		if ref := r.Lookup(sliceType.LengthIdentifier.Name); ref != nil && r.constants[ref] {
+			sliceType.LengthIdentifier.Resolved = ref
+			t = &ast.ArrayType{
+				Element:  sliceType.Element,
+				Constant: sliceType.LengthIdentifier,
+			}
+		}
+	}
+	return t, t.Accept(r)
+}
+
+// resolveElementType resolves a type nested in another type, which can't bind
+// a slice length.
+func (r *Resolver) resolveElementType(t ast.Type) (ast.Type, error) {
+	t, err := r.resolveType(t)
+	if sliceType, ok := t.(*ast.SliceType); ok && sliceType.LengthIdentifier != nil {
+		err = errors.Join(err, lengthBindingError(sliceType, "only the outermost slice type can bind its length"))
+	}
+	return t, err
+}
+
+func lengthBindingError(t *ast.SliceType, message string) error {
+	position := t.LengthIdentifier.Position
+	return fmt.Errorf("%s %s\n%s", position.String(), message, position.Snippet(len(t.LengthIdentifier.Name)))
 }
 func (r *Resolver) VisitLiteral(l *ast.Literal) error { return nil }
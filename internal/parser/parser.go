@@ -8,7 +8,7 @@ package parser
 
 import (
 	"fmt"
-	"strconv"
+	"strings"
 
 	"github.com/MisustinIvan/ilang/internal/ast"
 	"github.com/MisustinIvan/ilang/internal/lexer"
@@ -214,15 +214,15 @@ func (p *Parser) ParseExternalDeclaration() (*ast.ExternalDeclaration, error) {
 
 // ParseDeclaration parses a function declaration according to the grammar:
 //
-// declaration          ::= basic_type identifier "(" [ function_argument { "," function_argument } ] ")" block
+// declaration          ::= type identifier "(" [ function_argument { "," function_argument } ] ")" block
 func (p *Parser) ParseDeclaration() (*ast.Declaration, error) {
-	var Type *ast.BasicType
+	var Type ast.Type
 	var Identifier *ast.Identifier
 	var Arguments []ast.Argument
 	var Body *ast.Block
 
 	// parse type
-	Type, err := p.ParseBasicType()
+	Type, err := p.ParseType()
 	if err != nil {
 		return nil, err
 	}
@@ -275,13 +275,129 @@ func (p *Parser) ParseDeclaration() (*ast.Declaration, error) {
 	}
 
 	return &ast.Declaration{
-		Type:       *Type,
+		Type:       Type,
 		Identifier: Identifier,
 		Args:       Arguments,
 		Body:       *Body,
 	}, nil
 }
 
+// ParseStructDeclaration parses a struct declaration according to the grammar:
+//
+// struct_declaration   ::= "struct" identifier "{" { struct_field ";" } "}"
+// struct_field         ::= type identifier
+func (p *Parser) ParseStructDeclaration() (*ast.StructDeclaration, error) {
+	var Fields []ast.Field
+
+	if _, err := p.Expect(lexer.Keyword, lexer.KeywordStruct); err != nil {
+		return nil, err
+	}
+
+	Identifier, err := p.ParseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "{"); err != nil {
+		return nil, err
+	}
+
+	for !p.matchCurrent(lexer.Punctuator, "}") {
+		Type, err := p.ParseType()
+		if err != nil {
+			return nil, err
+		}
+
+		FieldIdentifier, err := p.ParseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.Expect(lexer.Punctuator, ";"); err != nil {
+			return nil, err
+		}
+
+		Fields = append(Fields, ast.Field{
+			Type:       Type,
+			Identifier: FieldIdentifier,
+		})
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "}"); err != nil {
+		return nil, err
+	}
+
+	return &ast.StructDeclaration{
+		Identifier: Identifier,
+		Fields:     Fields,
+	}, nil
+}
+
+// ParseEnumDeclaration parses an enum declaration according to the grammar:
+//
+// enum_declaration     ::= "enum" identifier "{" enum_variant { "," enum_variant } [ "," ] "}"
+// enum_variant         ::= identifier [ "=" value ]
+func (p *Parser) ParseEnumDeclaration() (*ast.EnumDeclaration, error) {
+	var Variants []ast.EnumVariant
+
+	enum_tk, err := p.Expect(lexer.Keyword, lexer.KeywordEnum)
+	if err != nil {
+		return nil, err
+	}
+
+	Identifier, err := p.ParseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "{"); err != nil {
+		return nil, err
+	}
+
+	for !p.matchCurrent(lexer.Punctuator, "}") {
+		VariantIdentifier, err := p.ParseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+
+		var Value ast.Value
+		if p.matchCurrent(lexer.Operator, "=") {
+			if _, err := p.next(); err != nil {
+				return nil, err
+			}
+			Value, err = p.ParseValue()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		Variants = append(Variants, ast.EnumVariant{
+			Identifier: VariantIdentifier,
+			Value:      Value,
+		})
+
+		if !p.matchCurrent(lexer.Punctuator, ",") {
+			break
+		}
+		if _, err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "}"); err != nil {
+		return nil, err
+	}
+
+	if len(Variants) == 0 {
+		return nil, parseError("enum must have at least one variant", enum_tk.Position)
+	}
+
+	return &ast.EnumDeclaration{
+		Identifier: Identifier,
+		Variants:   Variants,
+	}, nil
+}
+
 // ParseBlock parses a block expesssion according to the grammar:
 //
 // block                ::= "{" { expression ";" } [ expression ] "}"
@@ -330,6 +446,8 @@ func (p *Parser) ParseBlock() (*ast.Block, error) {
 // ParseExpression parses an expression according to the grammar:
 //
 //	expression           ::= return
+//	                       | break
+//	                       | continue
 //	                       | bind
 //	                       | assignment
 //	                       | value
@@ -337,37 +455,21 @@ func (p *Parser) ParseExpression() (ast.Expression, error) {
 	switch {
 	case p.matchCurrent(lexer.Keyword, lexer.KeywordReturn):
 		return p.ParseReturn()
+	case p.matchCurrent(lexer.Keyword, lexer.KeywordBreak):
+		return p.ParseBreak()
+	case p.matchCurrent(lexer.Keyword, lexer.KeywordContinue):
+		return p.ParseContinue()
 	case p.matchCurrent(lexer.Keyword, lexer.KeywordLet):
 		return p.ParseBind()
-	case p.matchCurrent(lexer.Identifier, "") && p.matchNext(lexer.Operator, "=", 1):
-		fallthrough
-	case p.matchCurrent(lexer.Operator, "@") && p.matchNext(lexer.Identifier, "", 1):
-		return p.ParseAssignment()
-	case p.matchCurrent(lexer.Identifier, "") && p.matchNext(lexer.Punctuator, "[", 1):
-		idx, err := p.ParseIndex()
+	default:
+		value, err := p.ParseValue()
 		if err != nil {
 			return nil, err
 		}
 		if p.matchCurrent(lexer.Operator, "=") {
-			_, err := p.Expect(lexer.Operator, "=")
-			if err != nil {
-				return nil, err
-			}
-			val, err := p.ParseValue()
-			if err != nil {
-				return nil, err
-			}
-			assignment := &ast.Assignment{
-				Target: idx,
-				Value:  val,
-			}
-			assignment.SetPosition(idx.GetPosition())
-			return assignment, nil
-		} else {
-			return p.parseBinary(idx, 0)
+			return p.ParseAssignment(value)
 		}
-	default:
-		return p.ParseValue()
+		return value, nil
 	}
 }
 
@@ -395,6 +497,36 @@ func (p *Parser) ParseReturn() (*ast.Return, error) {
 	return ret, nil
 }
 
+// ParseBreak parses a break expression according to the grammar:
+//
+// break                ::= "break"
+func (p *Parser) ParseBreak() (*ast.Break, error) {
+	breakToken, err := p.Expect(lexer.Keyword, lexer.KeywordBreak)
+	if err != nil {
+		return nil, err
+	}
+
+	brk := &ast.Break{}
+	brk.SetPosition(breakToken.Position)
+
+	return brk, nil
+}
+
+// ParseContinue parses a continue expression according to the grammar:
+//
+// continue             ::= "continue"
+func (p *Parser) ParseContinue() (*ast.Continue, error) {
+	continueToken, err := p.Expect(lexer.Keyword, lexer.KeywordContinue)
+	if err != nil {
+		return nil, err
+	}
+
+	cont := &ast.Continue{}
+	cont.SetPosition(continueToken.Position)
+
+	return cont, nil
+}
+
 // ParseBind parses a bind expression according to the grammar:
 //
 // bind                 ::= "let" identifier ":" type "=" value
@@ -445,44 +577,37 @@ func (p *Parser) ParseBind() (*ast.Bind, error) {
 
 // ParseDereference parses a dereference according to the grammar:
 //
-// deref                ::= "@" identifier
+// deref                ::= "@" primary
+//
+// The dereference binds tighter than indexing and field access, so @p[i]
+// indexes the value p points to.
 func (p *Parser) ParseDereference() (*ast.Dereference, error) {
 	if _, err := p.Expect(lexer.Operator, "@"); err != nil {
 		return nil, err
 	}
 
-	Identifier, err := p.ParseIdentifier()
+	value, err := p.parseOperand()
 	if err != nil {
 		return nil, err
 	}
 
 	deref := &ast.Dereference{
-		Value: Identifier,
+		Value: value,
 	}
-	deref.SetPosition(Identifier.GetPosition())
+	deref.SetPosition(value.GetPosition())
 
 	return deref, nil
 }
 
-// ParseAssignment parses an assignment expression according to the grammar:
-// (without the index, just the identifier)
+// ParseAssignment parses the rest of an assignment expression according to the
+// grammar, the target has already been parsed as a value:
 //
-// assignment           ::= identifier | index | deref "=" value
-func (p *Parser) ParseAssignment() (*ast.Assignment, error) {
-	var target ast.Primary
-
-	if p.matchCurrent(lexer.Operator, "@") {
-		deref, err := p.ParseDereference()
-		if err != nil {
-			return nil, err
-		}
-		target = deref
-	} else {
-		id, err := p.ParseIdentifier()
-		if err != nil {
-			return nil, err
-		}
-		target = id
+// assignment           ::= identifier | index | deref | field_access "=" value
+func (p *Parser) ParseAssignment(target ast.Value) (*ast.Assignment, error) {
+	switch target.(type) {
+	case *ast.Identifier, *ast.Index, *ast.Dereference, *ast.FieldAccess:
+	default:
+		return nil, parseError("invalid assignment target", target.GetPosition())
 	}
 
 	_, err := p.Expect(lexer.Operator, "=")
@@ -526,13 +651,19 @@ func (p *Parser) ParseValue() (ast.Value, error) {
 	return p.parseBinary(primary, 0)
 }
 
+// parseBinary parses the operators following the left operand by precedence
+// climbing. An operator after an operand is always binary, so "^" here is xor,
+// while in front of an operand ParseUnary reads it as address-of.
 func (p *Parser) parseBinary(left ast.Value, minPrec int) (ast.Value, error) {
 	for {
 		operatorToken := p.peek()
 		if operatorToken == nil || operatorToken.Kind != lexer.Operator {
 			break
 		}
-		operator := ast.BinaryOperatorTokens[operatorToken.Value]
+		operator, ok := ast.BinaryOperatorTokens[operatorToken.Value]
+		if !ok {
+			break
+		}
 		prec := ast.BinaryOperatorPrecedence[operator]
 		if prec < minPrec {
 			break
@@ -543,9 +674,7 @@ func (p *Parser) parseBinary(left ast.Value, minPrec int) (ast.Value, error) {
 		}
 		var right ast.Value
 		var err error
-		if p.matchCurrent(lexer.Operator, "@") {
-			right, err = p.ParseDereference()
-		} else if p.matchCurrent(lexer.Operator, "") {
+		if p.matchCurrent(lexer.Operator, "") && !p.matchCurrent(lexer.Operator, "@") {
 			right, err = p.ParseUnary()
 		} else {
 			right, err = p.ParsePrimary()
@@ -578,16 +707,83 @@ func (p *Parser) parseBinary(left ast.Value, minPrec int) (ast.Value, error) {
 //	                       | loop
 //	                       | make
 //	                       | release
+//	                       | field_access
+//	                       | conversion
+//	                       | length
+//	                       | append
+//	                       | copy
+//	                       | variant_access
+//	                       | match
 func (p *Parser) ParsePrimary() (ast.Primary, error) {
+	primary, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parsePostfix(primary)
+}
+
+// parsePostfix parses any postfix operations applied to an already parsed
+// primary expression according to the grammar:
+//
+// field_access         ::= primary "." identifier
+// index                ::= primary "[" value "]"
+// slice                ::= primary "[" [ value ] ":" [ value ] "]"
+// call                 ::= primary "(" [ value { "," value } ] ")"
+func (p *Parser) parsePostfix(primary ast.Primary) (ast.Primary, error) {
+	for {
+		switch {
+		case p.matchCurrent(lexer.Punctuator, "."):
+			if _, err := p.next(); err != nil {
+				return nil, err
+			}
+
+			Field, err := p.ParseIdentifier()
+			if err != nil {
+				return nil, err
+			}
+
+			fieldAccess := &ast.FieldAccess{
+				Value: primary,
+				Field: Field,
+			}
+			fieldAccess.SetPosition(primary.GetPosition())
+			primary = fieldAccess
+		case p.matchCurrent(lexer.Punctuator, "["):
+			index, err := p.ParseIndex(primary)
+			if err != nil {
+				return nil, err
+			}
+			primary = index
+		case p.matchCurrent(lexer.Punctuator, "("):
+			call, err := p.ParseCall(primary)
+			if err != nil {
+				return nil, err
+			}
+			primary = call
+		default:
+			return primary, nil
+		}
+	}
+}
+
+// parseOperand parses a primary expression without any postfix operations.
+func (p *Parser) parseOperand() (ast.Primary, error) {
 	switch {
 	case p.matchCurrent(lexer.Operator, "@"):
 		return p.ParseDereference()
 	case p.matchCurrent(lexer.Literal, ""):
 		return p.ParseLiteral()
-	case p.matchCurrent(lexer.Identifier, "") && p.matchNext(lexer.Punctuator, "(", 1):
-		return p.ParseCall()
-	case p.matchCurrent(lexer.Identifier, "") && p.matchNext(lexer.Punctuator, "[", 1):
-		return p.ParseIndex()
+	case p.matchConversion():
+		return p.ParseConversion()
+	case p.matchCurrent(lexer.Identifier, "len") && p.matchNext(lexer.Punctuator, "(", 1):
+		return p.ParseLength()
+	case p.matchCurrent(lexer.Identifier, "append") && p.matchNext(lexer.Punctuator, "(", 1):
+		return p.ParseAppend()
+	case p.matchCurrent(lexer.Identifier, "copy") && p.matchNext(lexer.Punctuator, "(", 1):
+		return p.ParseCopy()
+	case p.matchCurrent(lexer.Identifier, "") && p.matchNext(lexer.Punctuator, "::", 1):
+		return p.ParseVariantAccess()
 	case p.matchCurrent(lexer.Identifier, ""):
 		return p.ParseIdentifier()
 	case p.matchCurrent(lexer.Punctuator, "("):
@@ -596,6 +792,8 @@ func (p *Parser) ParsePrimary() (ast.Primary, error) {
 		return p.ParseBlock()
 	case p.matchCurrent(lexer.Keyword, lexer.KeywordIf):
 		return p.ParseCondition()
+	case p.matchCurrent(lexer.Keyword, lexer.KeywordMatch):
+		return p.ParseMatch()
 	case p.matchCurrent(lexer.Punctuator, "["):
 		return p.ParseArrayLiteral()
 	case p.matchCurrent(lexer.Keyword, lexer.KeywordFor):
@@ -613,11 +811,88 @@ func (p *Parser) ParsePrimary() (ast.Primary, error) {
 	}
 }
 
-// ParseMake parses a make expression according to the grammar:
+// matchConversion reports whether a conversion starts at the head, that is a
+// convertible type name or a slice type followed by an opening parenthesis.
+func (p *Parser) matchConversion() bool {
+	// an empty array literal is never followed by a type name
+	if p.matchCurrent(lexer.Punctuator, "[") && p.matchNext(lexer.Punctuator, "]", 1) {
+		return p.matchNext(lexer.Identifier, "", 2)
+	}
+	if !p.matchNext(lexer.Punctuator, "(", 1) || !p.matchCurrent(lexer.Identifier, "") {
+		return false
+	}
+	t, isBasic := basicTypes[p.peek().Value]
+	_, convertible := ast.ConversionApplies[t]
+	return isBasic && convertible
+}
+
+// ParseConversion parses a conversion according to the grammar:
 //
-// make                 ::= "make" "(" basic_type "," value ")"
-func (p *Parser) ParseMake() (*ast.Make, error) {
-	makeToken, err := p.Expect(lexer.Keyword, lexer.KeywordMake)
+// conversion           ::= ( integer_type | "float" | "bool" | "string" | "[" "]" type ) "(" value ")"
+func (p *Parser) ParseConversion() (*ast.Conversion, error) {
+	typeToken := p.peek()
+	t, err := p.ParseType()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "("); err != nil {
+		return nil, err
+	}
+
+	value, err := p.ParseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, ")"); err != nil {
+		return nil, err
+	}
+
+	conversion := &ast.Conversion{
+		Type:  t,
+		Value: value,
+	}
+	conversion.SetPosition(typeToken.Position)
+
+	return conversion, nil
+}
+
+// ParseLength parses a length expression according to the grammar:
+//
+// length               ::= "len" "(" value ")"
+func (p *Parser) ParseLength() (*ast.Length, error) {
+	lenToken, err := p.Expect(lexer.Identifier, "len")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "("); err != nil {
+		return nil, err
+	}
+
+	value, err := p.ParseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, ")"); err != nil {
+		return nil, err
+	}
+
+	length := &ast.Length{
+		Value: value,
+	}
+	length.SetPosition(lenToken.Position)
+
+	return length, nil
+}
+
+// ParseAppend parses an append expression according to the grammar:
+//
+// append               ::= "append" "(" value "," value ")"
+func (p *Parser) ParseAppend() (*ast.Append, error) {
+	appendToken, err := p.Expect(lexer.Identifier, "append")
 	if err != nil {
 		return nil, err
 	}
@@ -626,7 +901,7 @@ func (p *Parser) ParseMake() (*ast.Make, error) {
 		return nil, err
 	}
 
-	Type, err := p.ParseBasicType()
+	slice, err := p.ParseValue()
 	if err != nil {
 		return nil, err
 	}
@@ -635,7 +910,7 @@ func (p *Parser) ParseMake() (*ast.Make, error) {
 		return nil, err
 	}
 
-	Length, err := p.ParseValue()
+	value, err := p.ParseValue()
 	if err != nil {
 		return nil, err
 	}
@@ -644,8 +919,93 @@ func (p *Parser) ParseMake() (*ast.Make, error) {
 		return nil, err
 	}
 
+	a := &ast.Append{
+		Slice: slice,
+		Value: value,
+	}
+	a.SetPosition(appendToken.Position)
+
+	return a, nil
+}
+
+// ParseCopy parses a copy expression according to the grammar:
+//
+// copy                 ::= "copy" "(" value "," value ")"
+func (p *Parser) ParseCopy() (*ast.Copy, error) {
+	copyToken, err := p.Expect(lexer.Identifier, "copy")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "("); err != nil {
+		return nil, err
+	}
+
+	destination, err := p.ParseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, ","); err != nil {
+		return nil, err
+	}
+
+	source, err := p.ParseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, ")"); err != nil {
+		return nil, err
+	}
+
+	c := &ast.Copy{
+		Destination: destination,
+		Source:      source,
+	}
+	c.SetPosition(copyToken.Position)
+
+	return c, nil
+}
+
+// ParseMake parses a make expression according to the grammar:
+//
+// make                 ::= "make" "(" type ["," value] ")"
+//
+// Without a length a single value is allocated and the type must be a
+// pointer type.
+func (p *Parser) ParseMake() (*ast.Make, error) {
+	makeToken, err := p.Expect(lexer.Keyword, lexer.KeywordMake)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "("); err != nil {
+		return nil, err
+	}
+
+	Type, err := p.ParseType()
+	if err != nil {
+		return nil, err
+	}
+
+	var Length ast.Value
+	if p.matchCurrent(lexer.Punctuator, ",") {
+		if _, err := p.next(); err != nil {
+			return nil, err
+		}
+		Length, err = p.ParseValue()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, ")"); err != nil {
+		return nil, err
+	}
+
 	Make := &ast.Make{
-		Type:   *Type,
+		Type:   Type,
 		Length: Length,
 	}
 	Make.SetPosition(makeToken.Position)
@@ -655,7 +1015,7 @@ func (p *Parser) ParseMake() (*ast.Make, error) {
 
 // ParseRelease parses a release expression according to the grammar:
 //
-// release              ::= "release" "(" identifier ")"
+// release              ::= "release" "(" value ")"
 func (p *Parser) ParseRelease() (*ast.Release, error) {
 	releaseToken, err := p.Expect(lexer.Keyword, lexer.KeywordRelease)
 	if err != nil {
@@ -666,7 +1026,7 @@ func (p *Parser) ParseRelease() (*ast.Release, error) {
 		return nil, err
 	}
 
-	Value, err := p.ParseIdentifier()
+	Value, err := p.ParseValue()
 	if err != nil {
 		return nil, err
 	}
@@ -770,17 +1130,13 @@ func (p *Parser) ParseLiteral() (*ast.Literal, error) {
 
 // ParseCall parses a call expression according to the grammar:
 //
-// call                 ::= identifier "(" [ value { "," value } ] ")"
-func (p *Parser) ParseCall() (*ast.Call, error) {
-	var Identifier *ast.Identifier
+// call                 ::= primary "(" [ value { "," value } ] ")"
+//
+// The called primary is parsed by the caller, so that calls can be chained.
+func (p *Parser) ParseCall(function ast.Primary) (*ast.Call, error) {
 	var Arguments []ast.Value
 
-	Identifier, err := p.ParseIdentifier()
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = p.Expect(lexer.Punctuator, "(")
+	_, err := p.Expect(lexer.Punctuator, "(")
 	if err != nil {
 		return nil, err
 	}
@@ -808,10 +1164,10 @@ func (p *Parser) ParseCall() (*ast.Call, error) {
 	}
 
 	call := &ast.Call{
-		Identifier: Identifier,
-		Arguments:  Arguments,
+		Function:  function,
+		Arguments: Arguments,
 	}
-	call.SetPosition(Identifier.Position)
+	call.SetPosition(function.GetPosition())
 
 	return call, nil
 }
@@ -891,41 +1247,207 @@ func (p *Parser) ParseCondition() (*ast.Condition, error) {
 	return condition, nil
 }
 
-// ParseIndex parses an index expression according to the grammar:
+// ParseVariantAccess parses a variant of an enum according to the grammar:
 //
-// index                ::= identifier "[" primary "]"
-func (p *Parser) ParseIndex() (*ast.Index, error) {
-	var Identifier *ast.Identifier
-	var Index ast.Primary
+// variant_access       ::= identifier "::" identifier
+func (p *Parser) ParseVariantAccess() (*ast.VariantAccess, error) {
+	Enum, err := p.ParseIdentifier()
+	if err != nil {
+		return nil, err
+	}
 
-	Identifier, err := p.ParseIdentifier()
+	if _, err := p.Expect(lexer.Punctuator, "::"); err != nil {
+		return nil, err
+	}
+
+	Variant, err := p.ParseIdentifier()
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err := p.Expect(lexer.Punctuator, "["); err != nil {
+	variantAccess := &ast.VariantAccess{
+		Enum:    Enum,
+		Variant: Variant,
+	}
+	variantAccess.SetPosition(Enum.GetPosition())
+
+	return variantAccess, nil
+}
+
+// ParseMatch parses a match expression according to the grammar:
+//
+// match                ::= "match" value "{" match_arm { "," match_arm } [ "," ] "}"
+// match_arm            ::= ( pattern { "|" pattern } | "else" ) "=>" value
+// pattern              ::= primary | unary
+//
+// The else arm has to be the last one.
+func (p *Parser) ParseMatch() (*ast.Match, error) {
+	var Arms []ast.MatchArm
+	var Else ast.Value
+
+	match_tk, err := p.Expect(lexer.Keyword, lexer.KeywordMatch)
+	if err != nil {
 		return nil, err
 	}
 
-	Index, err = p.ParseValue()
+	Value, err := p.ParseValue()
 	if err != nil {
 		return nil, err
 	}
 
+	if _, err := p.Expect(lexer.Punctuator, "{"); err != nil {
+		return nil, err
+	}
+
+	for !p.matchCurrent(lexer.Punctuator, "}") {
+		if Else != nil {
+			return nil, parseError("else arm must be the last arm of the match", Else.GetPosition())
+		}
+
+		var Patterns []ast.Value
+		isElse := p.matchCurrent(lexer.Keyword, lexer.KeywordElse)
+		if isElse {
+			if _, err := p.next(); err != nil {
+				return nil, err
+			}
+		} else {
+			for {
+				pattern, err := p.parsePattern()
+				if err != nil {
+					return nil, err
+				}
+				Patterns = append(Patterns, pattern)
+
+				if !p.matchCurrent(lexer.Operator, "|") {
+					break
+				}
+				if _, err := p.next(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if _, err := p.Expect(lexer.Operator, "=>"); err != nil {
+			return nil, err
+		}
+
+		Body, err := p.ParseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if isElse {
+			Else = Body
+		} else {
+			Arms = append(Arms, ast.MatchArm{
+				Patterns: Patterns,
+				Body:     Body,
+			})
+		}
+
+		if !p.matchCurrent(lexer.Punctuator, ",") {
+			break
+		}
+		if _, err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "}"); err != nil {
+		return nil, err
+	}
+
+	if len(Arms) == 0 {
+		return nil, parseError("match must have at least one arm with a pattern", match_tk.Position)
+	}
+
+	match := &ast.Match{
+		Value: Value,
+		Arms:  Arms,
+		Else:  Else,
+	}
+	match.SetPosition(match_tk.Position)
+
+	return match, nil
+}
+
+// parsePattern parses a pattern of a match arm. Patterns are not parsed as
+// values, so that "|" separates them instead of being read as a binary
+// operator.
+func (p *Parser) parsePattern() (ast.Value, error) {
+	if p.matchCurrent(lexer.Operator, "") && !p.matchCurrent(lexer.Operator, "@") {
+		return p.ParseUnary()
+	}
+	return p.ParsePrimary()
+}
+
+// ParseIndex parses an index or a slice expression according to the grammar:
+//
+// index                ::= primary "[" value "]"
+// slice                ::= primary "[" [ value ] ":" [ value ] "]"
+//
+// The indexed primary is parsed by the caller, so that indexes can be chained.
+func (p *Parser) ParseIndex(value ast.Primary) (ast.Primary, error) {
+	if _, err := p.Expect(lexer.Punctuator, "["); err != nil {
+		return nil, err
+	}
+
+	var Index ast.Value
+	var err error
+	if !p.matchCurrent(lexer.Punctuator, ":") {
+		Index, err = p.ParseValue()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.matchCurrent(lexer.Punctuator, ":") {
+		return p.parseSlice(value, Index)
+	}
+
 	if _, err := p.Expect(lexer.Punctuator, "]"); err != nil {
 		return nil, err
 	}
 
 	idx := ast.Index{
-		Identifier: Identifier,
-		Index:      Index,
+		Value: value,
+		Index: Index,
 	}
 
-	idx.SetPosition(Identifier.Position)
+	idx.SetPosition(value.GetPosition())
 
 	return &idx, nil
 }
 
+// parseSlice parses the rest of a slice expression after its low bound.
+func (p *Parser) parseSlice(value ast.Primary, low ast.Value) (*ast.Slice, error) {
+	if _, err := p.Expect(lexer.Punctuator, ":"); err != nil {
+		return nil, err
+	}
+
+	var high ast.Value
+	var err error
+	if !p.matchCurrent(lexer.Punctuator, "]") {
+		high, err = p.ParseValue()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, "]"); err != nil {
+		return nil, err
+	}
+
+	slice := &ast.Slice{
+		Value: value,
+		Low:   low,
+		High:  high,
+	}
+	slice.SetPosition(value.GetPosition())
+
+	return slice, nil
+}
+
 // ParseUnary parses a unary expression according to the grammar:
 //
 // unary                ::= unary_operator primary
@@ -982,7 +1504,7 @@ func (p *Parser) ParseFunctionArgument() (*ast.Argument, error) {
 
 // ParseType parses a type according to the grammar:
 //
-// type                 ::= basic_type | array_type | slice_type | pointer_type
+// type                 ::= basic_type | array_type | slice_type | pointer_type | struct_type
 func (p *Parser) ParseType() (ast.Type, error) {
 	if p.matchCurrent(lexer.Punctuator, "[") {
 		return p.ParseBracketedType()
@@ -990,24 +1512,68 @@ func (p *Parser) ParseType() (ast.Type, error) {
 		if _, err := p.next(); err != nil {
 			return nil, err
 		}
-		t, err := p.ParseBasicType()
+		t, err := p.ParseType()
 		if err != nil {
 			return nil, err
 		}
 		return &ast.PointerType{
 			Inner: t,
 		}, nil
+	} else if p.matchCurrent(lexer.Keyword, lexer.KeywordFn) {
+		return p.ParseFunctionType()
 	} else if p.matchCurrent(lexer.Identifier, "") {
-		return p.ParseBasicType()
+		if _, isBasic := basicTypes[p.peek().Value]; isBasic {
+			return p.ParseBasicType()
+		}
+		return p.ParseStructType()
 	}
 	return nil, parseError("invalid type", p.peek().Position)
 }
 
+// ParseFunctionType parses the type of a pointer to a function.
+//
+// function_type        ::= "fn" "(" [type {"," type}] ")" type
+func (p *Parser) ParseFunctionType() (*ast.FunctionType, error) {
+	if _, err := p.Expect(lexer.Keyword, lexer.KeywordFn); err != nil {
+		return nil, err
+	}
+	if _, err := p.Expect(lexer.Punctuator, "("); err != nil {
+		return nil, err
+	}
+
+	parameters := []ast.Type{}
+	for !p.matchCurrent(lexer.Punctuator, ")") {
+		if len(parameters) > 0 {
+			if _, err := p.Expect(lexer.Punctuator, ","); err != nil {
+				return nil, err
+			}
+		}
+		t, err := p.ParseType()
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, t)
+	}
+	if _, err := p.Expect(lexer.Punctuator, ")"); err != nil {
+		return nil, err
+	}
+
+	returnType, err := p.ParseType()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.FunctionType{
+		Parameters: parameters,
+		Return:     returnType,
+	}, nil
+}
+
 // ParseBracketedType parses a type that starts with an opening bracket,
 // which can be either an array_type or a slice_type (anonymous or named).
 //
-// array_type           ::= "[" int_literal "]" basic_type
-// slice_type           ::= "[" [identifier] "]" basic_type
+// array_type           ::= "[" int_literal "]" type
+// slice_type           ::= "[" [identifier] "]" type
 func (p *Parser) ParseBracketedType() (ast.Type, error) {
 	if _, err := p.Expect(lexer.Punctuator, "["); err != nil {
 		return nil, err
@@ -1021,11 +1587,12 @@ func (p *Parser) ParseBracketedType() (ast.Type, error) {
 		if err != nil {
 			return nil, err
 		}
-		l, err := strconv.Atoi(tk.Value)
+		l, err := lexer.ParseIntLiteral(tk.Value)
 		if err != nil {
 			return nil, err
 		}
-		length = &l
+		n := int(l)
+		length = &n
 	} else if p.matchCurrent(lexer.Identifier, "") {
 		id, err := p.ParseIdentifier()
 		if err != nil {
@@ -1038,47 +1605,67 @@ func (p *Parser) ParseBracketedType() (ast.Type, error) {
 		return nil, err
 	}
 
-	basicType, err := p.ParseBasicType()
+	// whether the length identifier binds the length of a slice or names a
+	// constant is only known once the names are resolved
+	element, err := p.ParseType()
 	if err != nil {
 		return nil, err
 	}
 
 	if length != nil {
 		return &ast.ArrayType{
-			Element: *basicType,
+			Element: element,
 			Length:  *length,
 		}, nil
 	}
 
 	return &ast.SliceType{
-		Element:          *basicType,
+		Element:          element,
 		LengthIdentifier: lengthId,
 	}, nil
 }
 
+// ParseStructType parses a reference to a struct type according to the grammar:
+//
+// struct_type          ::= identifier
+func (p *Parser) ParseStructType() (*ast.StructType, error) {
+	Identifier, err := p.ParseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.StructType{
+		Identifier: Identifier,
+	}, nil
+}
+
+var basicTypes = map[string]ast.BasicType{
+	"int":    ast.Int,
+	"bool":   ast.Bool,
+	"float":  ast.Float,
+	"string": ast.String,
+	"unit":   ast.Unit,
+	"i8":     ast.I8,
+	"i16":    ast.I16,
+	"i32":    ast.I32,
+	"u8":     ast.U8,
+	"u16":    ast.U16,
+	"u32":    ast.U32,
+	"u64":    ast.U64,
+}
+
 // ParseBasicType parses a basic type according to the grammar:
 //
-// basic_type           ::= "int" | "bool" | "float" | "string" | "unit"
+// basic_type           ::= integer_type | "bool" | "float" | "string" | "unit"
+// integer_type         ::= "int" | "i8" | "i16" | "i32" | "u8" | "u16" | "u32" | "u64"
 func (p *Parser) ParseBasicType() (*ast.BasicType, error) {
-	var BasicType ast.BasicType
-
 	tk, err := p.Expect(lexer.Identifier, "")
 	if err != nil {
 		return nil, err
 	}
 
-	switch tk.Value {
-	case "int":
-		BasicType = ast.Int
-	case "bool":
-		BasicType = ast.Bool
-	case "float":
-		BasicType = ast.Float
-	case "string":
-		BasicType = ast.String
-	case "unit":
-		BasicType = ast.Unit
-	default:
+	BasicType, ok := basicTypes[tk.Value]
+	if !ok {
 		return nil, parseError(fmt.Sprintf("invalid type %s", tk.Value), tk.Position)
 	}
 
@@ -1100,17 +1687,132 @@ func (p *Parser) ParseIdentifier() (*ast.Identifier, error) {
 	return identifier, err
 }
 
-// Parses the tokens, returning the *ast.Program.
+// ParseImport parses an import of another module according to the grammar:
+//
+// import               ::= "import" string_literal
+func (p *Parser) ParseImport() (*ast.Import, error) {
+	if _, err := p.Expect(lexer.Keyword, lexer.KeywordImport); err != nil {
+		return nil, err
+	}
+
+	path, err := p.Expect(lexer.Literal, "")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(path.Value, "\"") {
+		return nil, parseError(fmt.Sprintf("expected string literal path of the imported module, got '%s'", path.Value), path.Position)
+	}
+
+	return &ast.Import{
+		Path:     path.Value[1 : len(path.Value)-1],
+		Position: path.Position,
+	}, nil
+}
+
+// ParseGlobal parses a global variable according to the grammar:
+//
+// global               ::= bind ";"
+func (p *Parser) ParseGlobal() (*ast.Global, error) {
+	bind, err := p.ParseBind()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, ";"); err != nil {
+		return nil, err
+	}
+
+	return &ast.Global{
+		Identifier: bind.Identifier,
+		Type:       bind.Type,
+		Value:      bind.Value,
+	}, nil
+}
+
+// ParseConstant parses a constant according to the grammar:
+//
+// constant             ::= "const" identifier ":" type "=" value ";"
+func (p *Parser) ParseConstant() (*ast.Constant, error) {
+	if _, err := p.Expect(lexer.Keyword, lexer.KeywordConst); err != nil {
+		return nil, err
+	}
+
+	Identifier, err := p.ParseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, ":"); err != nil {
+		return nil, err
+	}
+
+	Type, err := p.ParseType()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Operator, "="); err != nil {
+		return nil, err
+	}
+
+	Value, err := p.ParseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Expect(lexer.Punctuator, ";"); err != nil {
+		return nil, err
+	}
+
+	return &ast.Constant{
+		Identifier: Identifier,
+		Type:       Type,
+		Value:      Value,
+	}, nil
+}
+
+// Parses the tokens, returning the *ast.Program made of a single module.
 func (p *Parser) Parse() (*ast.Program, error) {
 	program := &ast.Program{}
+	module := &ast.Module{}
 
 	for p.headInBounds() {
-		if p.matchCurrent(lexer.Keyword, lexer.KeywordExtrn) {
+		if p.matchCurrent(lexer.Keyword, lexer.KeywordImport) {
+			imp, err := p.ParseImport()
+			if err != nil {
+				return nil, err
+			}
+			module.Imports = append(module.Imports, imp)
+		} else if p.matchCurrent(lexer.Keyword, lexer.KeywordExtrn) {
 			decl, err := p.ParseExternalDeclaration()
 			if err != nil {
 				return nil, err
 			}
 			program.ExternalDeclarations = append(program.ExternalDeclarations, decl)
+		} else if p.matchCurrent(lexer.Keyword, lexer.KeywordStruct) {
+			decl, err := p.ParseStructDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			program.StructDeclarations = append(program.StructDeclarations, decl)
+		} else if p.matchCurrent(lexer.Keyword, lexer.KeywordEnum) {
+			decl, err := p.ParseEnumDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			program.EnumDeclarations = append(program.EnumDeclarations, decl)
+		} else if p.matchCurrent(lexer.Keyword, lexer.KeywordLet) {
+			global, err := p.ParseGlobal()
+			if err != nil {
+				return nil, err
+			}
+			program.Globals = append(program.Globals, global)
+		} else if p.matchCurrent(lexer.Keyword, lexer.KeywordConst) {
+			constant, err := p.ParseConstant()
+			if err != nil {
+				return nil, err
+			}
+			program.Constants = append(program.Constants, constant)
 		} else {
 			decl, err := p.ParseDeclaration()
 			if err != nil {
@@ -1120,5 +1822,13 @@ func (p *Parser) Parse() (*ast.Program, error) {
 		}
 	}
 
+	module.Declarations = program.Declarations
+	module.ExternalDeclarations = program.ExternalDeclarations
+	module.StructDeclarations = program.StructDeclarations
+	module.EnumDeclarations = program.EnumDeclarations
+	module.Globals = program.Globals
+	module.Constants = program.Constants
+	program.Modules = []*ast.Module{module}
+
 	return program, nil
 }
@@ -114,6 +114,262 @@ func TestParseUnary(t *testing.T) {
 	}
 }
 
+func TestParseXorAndAddressOf(t *testing.T) {
+	input := "int main() { a ^ ^b ^ ~c }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	// (a ^ ^b) ^ ~c
+	outer, ok := program.Declarations[0].Body.ImplicitReturn.(*ast.Binary)
+	if !ok || outer.Operator != ast.BitXor {
+		t.Fatalf("Expected BitXor binary expression, got %T", program.Declarations[0].Body.ImplicitReturn)
+	}
+
+	not, ok := outer.Right.(*ast.Unary)
+	if !ok || not.Operator != ast.BitNot {
+		t.Fatalf("Expected BitNot unary expression on the right, got %T", outer.Right)
+	}
+
+	inner, ok := outer.Left.(*ast.Binary)
+	if !ok || inner.Operator != ast.BitXor {
+		t.Fatalf("Expected BitXor binary expression on the left, got %T", outer.Left)
+	}
+
+	addressOf, ok := inner.Right.(*ast.Unary)
+	if !ok || addressOf.Operator != ast.AddressOf {
+		t.Fatalf("Expected AddressOf unary expression, got %T", inner.Right)
+	}
+}
+
+func TestParseDereference(t *testing.T) {
+	input := "int main(^^[]int pp) { @@pp[1] }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	decl := program.Declarations[0]
+	outer, ok := decl.Args[0].Type.(*ast.PointerType)
+	if !ok {
+		t.Fatalf("Expected PointerType argument, got %T", decl.Args[0].Type)
+	}
+	inner, ok := outer.Inner.(*ast.PointerType)
+	if !ok {
+		t.Fatalf("Expected PointerType inside PointerType, got %T", outer.Inner)
+	}
+	if _, ok := inner.Inner.(*ast.SliceType); !ok {
+		t.Fatalf("Expected SliceType inside PointerType, got %T", inner.Inner)
+	}
+
+	// (@(@pp))[1]
+	index, ok := decl.Body.ImplicitReturn.(*ast.Index)
+	if !ok {
+		t.Fatalf("Expected Index, got %T", decl.Body.ImplicitReturn)
+	}
+	deref, ok := index.Value.(*ast.Dereference)
+	if !ok {
+		t.Fatalf("Expected Dereference as indexed value, got %T", index.Value)
+	}
+	if _, ok := deref.Value.(*ast.Dereference); !ok {
+		t.Fatalf("Expected Dereference inside Dereference, got %T", deref.Value)
+	}
+}
+
+func TestParseSlice(t *testing.T) {
+	tests := []struct {
+		input   string
+		hasLow  bool
+		hasHigh bool
+	}{
+		{"int main() { a[1:2] }", true, true},
+		{"int main() { a[1:] }", true, false},
+		{"int main() { a[:2] }", false, true},
+		{"int main() { a[:] }", false, false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(lexer.NewSourceFile("test", tt.input))
+		tokens, err := l.Lex()
+		if err != nil {
+			t.Fatalf("Lexing failed: %v", err)
+		}
+
+		p := New(tokens)
+		program, err := p.Parse()
+		if err != nil {
+			tFatalf(t, "Parsing failed: %v", err)
+		}
+
+		slice, ok := program.Declarations[0].Body.ImplicitReturn.(*ast.Slice)
+		if !ok {
+			t.Fatalf("%s: expected Slice, got %T", tt.input, program.Declarations[0].Body.ImplicitReturn)
+		}
+		if (slice.Low != nil) != tt.hasLow {
+			t.Fatalf("%s: expected low bound %v, got %v", tt.input, tt.hasLow, slice.Low)
+		}
+		if (slice.High != nil) != tt.hasHigh {
+			t.Fatalf("%s: expected high bound %v, got %v", tt.input, tt.hasHigh, slice.High)
+		}
+	}
+}
+
+func TestParseLength(t *testing.T) {
+	input := "int main() { len(a[1:]) + len(b) }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	binary, ok := program.Declarations[0].Body.ImplicitReturn.(*ast.Binary)
+	if !ok {
+		t.Fatalf("Expected Binary, got %T", program.Declarations[0].Body.ImplicitReturn)
+	}
+
+	left, ok := binary.Left.(*ast.Length)
+	if !ok {
+		t.Fatalf("Expected Length on the left, got %T", binary.Left)
+	}
+	if _, ok := left.Value.(*ast.Slice); !ok {
+		t.Fatalf("Expected Slice inside Length, got %T", left.Value)
+	}
+
+	if _, ok := binary.Right.(*ast.Length); !ok {
+		t.Fatalf("Expected Length on the right, got %T", binary.Right)
+	}
+}
+
+func TestParseAppendAndCopy(t *testing.T) {
+	input := "int main() { s = append(s, 1 + 2); copy(d, s[1:]) }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	body := program.Declarations[0].Body
+	assignment, ok := body.Body[0].(*ast.Assignment)
+	if !ok {
+		t.Fatalf("Expected Assignment, got %T", body.Body[0])
+	}
+	appendExpr, ok := assignment.Value.(*ast.Append)
+	if !ok {
+		t.Fatalf("Expected Append, got %T", assignment.Value)
+	}
+	if _, ok := appendExpr.Value.(*ast.Binary); !ok {
+		t.Fatalf("Expected Binary as the appended value, got %T", appendExpr.Value)
+	}
+
+	copyExpr, ok := body.ImplicitReturn.(*ast.Copy)
+	if !ok {
+		t.Fatalf("Expected Copy, got %T", body.ImplicitReturn)
+	}
+	if _, ok := copyExpr.Source.(*ast.Slice); !ok {
+		t.Fatalf("Expected Slice as the copy source, got %T", copyExpr.Source)
+	}
+}
+
+func TestParseMakeAndRelease(t *testing.T) {
+	input := "int main() { let p: ^int = make(^int); let s: [][4]float = make([4]float, n); release(p) }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	body := program.Declarations[0].Body
+	single, ok := body.Body[0].(*ast.Bind).Value.(*ast.Make)
+	if !ok {
+		t.Fatalf("Expected Make, got %T", body.Body[0].(*ast.Bind).Value)
+	}
+	if _, ok := single.Type.(*ast.PointerType); !ok || single.Length != nil {
+		t.Fatalf("Expected make of a pointer type without a length, got %v with length %v", single.Type, single.Length)
+	}
+
+	slice, ok := body.Body[1].(*ast.Bind).Value.(*ast.Make)
+	if !ok {
+		t.Fatalf("Expected Make, got %T", body.Body[1].(*ast.Bind).Value)
+	}
+	if _, ok := slice.Type.(*ast.ArrayType); !ok || slice.Length == nil {
+		t.Fatalf("Expected make of an array type with a length, got %v with length %v", slice.Type, slice.Length)
+	}
+
+	release, ok := body.ImplicitReturn.(*ast.Release)
+	if !ok {
+		t.Fatalf("Expected Release, got %T", body.ImplicitReturn)
+	}
+	if _, ok := release.Value.(*ast.Identifier); !ok {
+		t.Fatalf("Expected Identifier as the released value, got %T", release.Value)
+	}
+}
+
+func TestParseConversion(t *testing.T) {
+	input := "int main() { int(float(x) * 2.5) }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	outer, ok := program.Declarations[0].Body.ImplicitReturn.(*ast.Conversion)
+	if !ok {
+		t.Fatalf("Expected Conversion, got %T", program.Declarations[0].Body.ImplicitReturn)
+	}
+	if !outer.Type.Equals(ast.BasicTypePtr(ast.Int)) {
+		t.Fatalf("Expected conversion to %s, got %s", ast.Int, outer.Type)
+	}
+
+	binary, ok := outer.Value.(*ast.Binary)
+	if !ok {
+		t.Fatalf("Expected Binary value, got %T", outer.Value)
+	}
+
+	inner, ok := binary.Left.(*ast.Conversion)
+	if !ok || !inner.Type.Equals(ast.BasicTypePtr(ast.Float)) {
+		t.Fatalf("Expected conversion to %s on the left, got %T", ast.Float, binary.Left)
+	}
+}
+
 func tFatalf(t *testing.T, format string, args ...any) {
 	t.Helper()
 	t.Fatalf(format, args...)
@@ -362,8 +618,8 @@ func TestParseArrayIndex(t *testing.T) {
 		t.Fatalf("Expected Index expression, got %T", binary.Left)
 	}
 
-	if index.Identifier.Name != "a" {
-		t.Fatalf("Expected identifier 'a', got '%s'", index.Identifier.Name)
+	if id, ok := index.Value.(*ast.Identifier); !ok || id.Name != "a" {
+		t.Fatalf("Expected identifier 'a', got %T", index.Value)
 	}
 
 	idxLiteral, ok := index.Index.(*ast.Literal)
@@ -375,6 +631,62 @@ func TestParseArrayIndex(t *testing.T) {
 	}
 }
 
+func TestParseNestedIndex(t *testing.T) {
+	input := "int main() { let grid: [2][3]int = 0; grid[1][2] = grid[0][1]; }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	decl := program.Declarations[0]
+	bind, ok := decl.Body.Body[0].(*ast.Bind)
+	if !ok {
+		t.Fatalf("Expected Bind expression, got %T", decl.Body.Body[0])
+	}
+
+	expectedType := &ast.ArrayType{
+		Element: &ast.ArrayType{Element: ast.BasicTypePtr(ast.Int), Length: 3},
+		Length:  2,
+	}
+	if !bind.Type.Equals(expectedType) {
+		t.Fatalf("Expected type %s, got %s", expectedType, bind.Type)
+	}
+
+	assign, ok := decl.Body.Body[1].(*ast.Assignment)
+	if !ok {
+		t.Fatalf("Expected Assignment expression, got %T", decl.Body.Body[1])
+	}
+
+	outer, ok := assign.Target.(*ast.Index)
+	if !ok {
+		t.Fatalf("Expected Index expression as assignment target, got %T", assign.Target)
+	}
+
+	inner, ok := outer.Value.(*ast.Index)
+	if !ok {
+		t.Fatalf("Expected nested Index expression, got %T", outer.Value)
+	}
+
+	if id, ok := inner.Value.(*ast.Identifier); !ok || id.Name != "grid" {
+		t.Fatalf("Expected identifier 'grid', got %T", inner.Value)
+	}
+
+	if literal := outer.Index.(*ast.Literal); literal.Value != "2" {
+		t.Fatalf("Expected outer index to be '2', got '%s'", literal.Value)
+	}
+
+	if _, ok := assign.Value.(*ast.Index); !ok {
+		t.Fatalf("Expected Index expression as assigned value, got %T", assign.Value)
+	}
+}
+
 func TestParseArrayAssignment(t *testing.T) {
 	input := "int main() { a[2] = 1; }"
 	l := lexer.New(lexer.NewSourceFile("test", input))
@@ -400,8 +712,8 @@ func TestParseArrayAssignment(t *testing.T) {
 		t.Fatalf("Expected Index expression as assignment target, got %T", assign.Target)
 	}
 
-	if index.Identifier.Name != "a" {
-		t.Fatalf("Expected identifier 'a', got '%s'", index.Identifier.Name)
+	if id, ok := index.Value.(*ast.Identifier); !ok || id.Name != "a" {
+		t.Fatalf("Expected identifier 'a', got %T", index.Value)
 	}
 
 	val, ok := assign.Value.(*ast.Literal)
@@ -413,6 +725,512 @@ func TestParseArrayAssignment(t *testing.T) {
 	}
 }
 
+func TestParseStruct(t *testing.T) {
+	input := `
+	struct Point {
+		int x;
+		float y;
+	}
+
+	Point origin() { let p: Point = 0; p.x = 1; p.x }
+	`
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	if len(program.StructDeclarations) != 1 {
+		t.Fatalf("Expected 1 struct declaration, got %d", len(program.StructDeclarations))
+	}
+
+	decl := program.StructDeclarations[0]
+	if decl.Identifier.Name != "Point" {
+		t.Fatalf("Expected struct name 'Point', got '%s'", decl.Identifier.Name)
+	}
+
+	if len(decl.Fields) != 2 {
+		t.Fatalf("Expected 2 fields, got %d", len(decl.Fields))
+	}
+
+	if decl.Fields[1].Identifier.Name != "y" || !decl.Fields[1].Type.Equals(ast.BasicTypePtr(ast.Float)) {
+		t.Fatalf("Expected field 'y' of type float, got '%s' of type %s", decl.Fields[1].Identifier.Name, decl.Fields[1].Type)
+	}
+
+	function := program.Declarations[0]
+	if _, ok := function.Type.(*ast.StructType); !ok {
+		t.Fatalf("Expected struct return type, got %T", function.Type)
+	}
+
+	assign, ok := function.Body.Body[1].(*ast.Assignment)
+	if !ok {
+		t.Fatalf("Expected Assignment expression, got %T", function.Body.Body[1])
+	}
+
+	target, ok := assign.Target.(*ast.FieldAccess)
+	if !ok {
+		t.Fatalf("Expected FieldAccess as assignment target, got %T", assign.Target)
+	}
+
+	if target.Field.Name != "x" {
+		t.Fatalf("Expected field 'x', got '%s'", target.Field.Name)
+	}
+
+	access, ok := function.Body.ImplicitReturn.(*ast.FieldAccess)
+	if !ok {
+		t.Fatalf("Expected FieldAccess expression, got %T", function.Body.ImplicitReturn)
+	}
+
+	if access.Value.(*ast.Identifier).Name != "p" {
+		t.Fatalf("Expected identifier 'p', got '%s'", access.Value.(*ast.Identifier).Name)
+	}
+}
+
+func TestParseImport(t *testing.T) {
+	input := `
+	import "std/io"
+	import "vector.ilang"
+
+	unit main() {}
+	`
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	if len(program.Modules) != 1 {
+		t.Fatalf("Expected 1 module, got %d", len(program.Modules))
+	}
+
+	module := program.Modules[0]
+	if len(module.Imports) != 2 {
+		t.Fatalf("Expected 2 imports, got %d", len(module.Imports))
+	}
+
+	if module.Imports[0].Path != "std/io" {
+		t.Fatalf("Expected import path 'std/io', got '%s'", module.Imports[0].Path)
+	}
+
+	if module.Imports[1].Path != "vector.ilang" {
+		t.Fatalf("Expected import path 'vector.ilang', got '%s'", module.Imports[1].Path)
+	}
+
+	if len(module.Declarations) != 1 || module.Declarations[0] != program.Declarations[0] {
+		t.Fatalf("Expected the module to hold the declarations of the program")
+	}
+
+	l = lexer.New(lexer.NewSourceFile("test", "import main"))
+	tokens, err = l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	if _, err := New(tokens).Parse(); err == nil {
+		t.Fatalf("Expected parsing to fail on import without a path")
+	}
+}
+
+func TestParseGlobalsAndConstants(t *testing.T) {
+	input := `
+	const SIZE: int = 4 * 2;
+	let counter: int = 0;
+	let buffer: [SIZE]int = 0;
+
+	int main() { counter }
+	`
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	if len(program.Constants) != 1 || len(program.Modules[0].Constants) != 1 {
+		t.Fatalf("Expected 1 constant, got %d", len(program.Constants))
+	}
+
+	constant := program.Constants[0]
+	if constant.Identifier.Name != "SIZE" {
+		t.Fatalf("Expected constant 'SIZE', got '%s'", constant.Identifier.Name)
+	}
+	if _, ok := constant.Value.(*ast.Binary); !ok {
+		t.Fatalf("Expected Binary as the constant value, got %T", constant.Value)
+	}
+
+	if len(program.Globals) != 2 || len(program.Modules[0].Globals) != 2 {
+		t.Fatalf("Expected 2 globals, got %d", len(program.Globals))
+	}
+
+	if program.Globals[0].Identifier.Name != "counter" {
+		t.Fatalf("Expected global 'counter', got '%s'", program.Globals[0].Identifier.Name)
+	}
+
+	// the length names a constant, which is only known once names are resolved
+	buffer, ok := program.Globals[1].Type.(*ast.SliceType)
+	if !ok || buffer.LengthIdentifier == nil || buffer.LengthIdentifier.Name != "SIZE" {
+		t.Fatalf("Expected slice type with length 'SIZE', got %v", program.Globals[1].Type)
+	}
+
+	for _, input := range []string{"let x: int = 0", "const X: int;", "const: int = 1;"} {
+		l = lexer.New(lexer.NewSourceFile("test", input))
+		tokens, err = l.Lex()
+		if err != nil {
+			t.Fatalf("Lexing failed: %v", err)
+		}
+
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Fatalf("Expected parsing to fail on %q", input)
+		}
+	}
+}
+
+func TestParseEnumAndMatch(t *testing.T) {
+	input := `
+	enum Color { Red, Green = 5, Blue, }
+
+	int main() {
+		match c {
+			Color::Red => 1,
+			Color::Green | Color::Blue => 2,
+			else => -1
+		}
+	}
+	`
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	if len(program.EnumDeclarations) != 1 {
+		t.Fatalf("Expected 1 enum declaration, got %d", len(program.EnumDeclarations))
+	}
+
+	enum := program.EnumDeclarations[0]
+	if enum.Identifier.Name != "Color" || len(enum.Variants) != 3 {
+		t.Fatalf("Expected enum 'Color' with 3 variants, got '%s' with %d", enum.Identifier.Name, len(enum.Variants))
+	}
+	if enum.Variants[0].Value != nil || enum.Variants[1].Value == nil {
+		t.Fatalf("Expected only the variant 'Green' to have a value")
+	}
+
+	match, ok := program.Declarations[0].Body.ImplicitReturn.(*ast.Match)
+	if !ok {
+		t.Fatalf("Expected Match expression, got %T", program.Declarations[0].Body.ImplicitReturn)
+	}
+
+	if len(match.Arms) != 2 {
+		t.Fatalf("Expected 2 arms, got %d", len(match.Arms))
+	}
+
+	if len(match.Arms[1].Patterns) != 2 {
+		t.Fatalf("Expected 2 patterns in the second arm, got %d", len(match.Arms[1].Patterns))
+	}
+
+	variant, ok := match.Arms[1].Patterns[1].(*ast.VariantAccess)
+	if !ok || variant.Enum.Name != "Color" || variant.Variant.Name != "Blue" {
+		t.Fatalf("Expected pattern Color::Blue, got %v", match.Arms[1].Patterns[1])
+	}
+
+	if _, ok := match.Else.(*ast.Unary); !ok {
+		t.Fatalf("Expected Unary as the else arm, got %T", match.Else)
+	}
+
+	for _, input := range []string{
+		"enum Empty {}",
+		"int main() { match x {} }",
+		"int main() { match x { else => 0, 1 => 1 } }",
+		"int main() { match x { 1 -> 1 } }",
+	} {
+		l = lexer.New(lexer.NewSourceFile("test", input))
+		tokens, err = l.Lex()
+		if err != nil {
+			t.Fatalf("Lexing failed: %v", err)
+		}
+
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Fatalf("Expected parsing to fail on %q", input)
+		}
+	}
+}
+
+func TestParseFunctionType(t *testing.T) {
+	input := `
+	extrn unit qsort(^int base, int count, int size, fn(^int, ^int) int compare)
+
+	fn() unit pick(fn(int, int) int f) {
+		let table: [2]fn(int) bool = 0;
+		f(1, 2);
+		done
+	}
+	`
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	compare, ok := program.ExternalDeclarations[0].Args[3].Type.(*ast.FunctionType)
+	if !ok || len(compare.Parameters) != 2 {
+		t.Fatalf("Expected function type with 2 parameters, got %v", program.ExternalDeclarations[0].Args[3].Type)
+	}
+	if _, ok := compare.Parameters[0].(*ast.PointerType); !ok {
+		t.Fatalf("Expected PointerType parameter, got %T", compare.Parameters[0])
+	}
+
+	decl := program.Declarations[0]
+	returned, ok := decl.Type.(*ast.FunctionType)
+	if !ok || len(returned.Parameters) != 0 || !returned.Return.Equals(ast.BasicTypePtr(ast.Unit)) {
+		t.Fatalf("Expected return type fn() unit, got %v", decl.Type)
+	}
+
+	bind, ok := decl.Body.Body[0].(*ast.Bind)
+	if !ok {
+		t.Fatalf("Expected Bind, got %T", decl.Body.Body[0])
+	}
+	table, ok := bind.Type.(*ast.ArrayType)
+	if !ok {
+		t.Fatalf("Expected ArrayType, got %T", bind.Type)
+	}
+	if _, ok := table.Element.(*ast.FunctionType); !ok {
+		t.Fatalf("Expected array of FunctionType, got %v", table.Element)
+	}
+
+	for _, input := range []string{
+		"unit f(fn(int) g) {}",
+		"unit f(fn int int g) {}",
+		"unit f(fn(int int) int g) {}",
+	} {
+		l = lexer.New(lexer.NewSourceFile("test", input))
+		tokens, err = l.Lex()
+		if err != nil {
+			t.Fatalf("Lexing failed: %v", err)
+		}
+
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Fatalf("Expected parsing to fail on %q", input)
+		}
+	}
+}
+
+func TestParseIndirectCall(t *testing.T) {
+	input := "int main() { table[i](1); get_fn()(2, 3) }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	decl := program.Declarations[0]
+	indexed, ok := decl.Body.Body[0].(*ast.Call)
+	if !ok {
+		t.Fatalf("Expected Call expression, got %T", decl.Body.Body[0])
+	}
+	index, ok := indexed.Function.(*ast.Index)
+	if !ok {
+		t.Fatalf("Expected Index expression as called function, got %T", indexed.Function)
+	}
+	if id, ok := index.Value.(*ast.Identifier); !ok || id.Name != "table" {
+		t.Fatalf("Expected identifier 'table', got %T", index.Value)
+	}
+	if len(indexed.Arguments) != 1 {
+		t.Fatalf("Expected 1 argument, got %d", len(indexed.Arguments))
+	}
+
+	chained, ok := decl.Body.ImplicitReturn.(*ast.Call)
+	if !ok {
+		t.Fatalf("Expected Call expression, got %T", decl.Body.ImplicitReturn)
+	}
+	inner, ok := chained.Function.(*ast.Call)
+	if !ok {
+		t.Fatalf("Expected Call expression as called function, got %T", chained.Function)
+	}
+	if id, ok := inner.Function.(*ast.Identifier); !ok || id.Name != "get_fn" || len(inner.Arguments) != 0 {
+		t.Fatalf("Expected call of 'get_fn' without arguments, got %T", inner.Function)
+	}
+	if len(chained.Arguments) != 2 {
+		t.Fatalf("Expected 2 arguments, got %d", len(chained.Arguments))
+	}
+}
+
+func TestParseSizedIntegers(t *testing.T) {
+	input := `
+	u32 hash([n]u8 bytes, ^i16 p) {
+		let x: u64 = 0;
+		i32(u8(x) + 1)
+	}
+	`
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	decl := program.Declarations[0]
+	if !decl.Type.Equals(ast.BasicTypePtr(ast.U32)) {
+		t.Fatalf("Expected return type u32, got %v", decl.Type)
+	}
+	bytes, ok := decl.Args[0].Type.(*ast.SliceType)
+	if !ok || !bytes.Element.Equals(ast.BasicTypePtr(ast.U8)) {
+		t.Fatalf("Expected slice of u8, got %v", decl.Args[0].Type)
+	}
+	pointer, ok := decl.Args[1].Type.(*ast.PointerType)
+	if !ok || !pointer.Inner.Equals(ast.BasicTypePtr(ast.I16)) {
+		t.Fatalf("Expected pointer to i16, got %v", decl.Args[1].Type)
+	}
+
+	bind, ok := decl.Body.Body[0].(*ast.Bind)
+	if !ok || !bind.Type.Equals(ast.BasicTypePtr(ast.U64)) {
+		t.Fatalf("Expected Bind of u64, got %v", decl.Body.Body[0])
+	}
+
+	outer, ok := decl.Body.ImplicitReturn.(*ast.Conversion)
+	if !ok || !outer.Type.Equals(ast.BasicTypePtr(ast.I32)) {
+		t.Fatalf("Expected Conversion to i32, got %v", decl.Body.ImplicitReturn)
+	}
+	binary, ok := outer.Value.(*ast.Binary)
+	if !ok {
+		t.Fatalf("Expected Binary, got %T", outer.Value)
+	}
+	if inner, ok := binary.Left.(*ast.Conversion); !ok || !inner.Type.Equals(ast.BasicTypePtr(ast.U8)) {
+		t.Fatalf("Expected Conversion to u8, got %v", binary.Left)
+	}
+}
+
+func TestParseStringConversion(t *testing.T) {
+	input := "int main() { let b: []u8 = []u8(s); string(b[1:]) == s }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	decl := program.Declarations[0]
+	bind, ok := decl.Body.Body[0].(*ast.Bind)
+	if !ok {
+		t.Fatalf("Expected Bind, got %T", decl.Body.Body[0])
+	}
+	bytes, ok := bind.Value.(*ast.Conversion)
+	if !ok {
+		t.Fatalf("Expected Conversion, got %T", bind.Value)
+	}
+	slice, ok := bytes.Type.(*ast.SliceType)
+	if !ok || !slice.Element.Equals(ast.BasicTypePtr(ast.U8)) {
+		t.Fatalf("Expected conversion to []u8, got %v", bytes.Type)
+	}
+
+	binary, ok := decl.Body.ImplicitReturn.(*ast.Binary)
+	if !ok || binary.Operator != ast.Equality {
+		t.Fatalf("Expected Equality, got %v", decl.Body.ImplicitReturn)
+	}
+	str, ok := binary.Left.(*ast.Conversion)
+	if !ok || !str.Type.Equals(ast.BasicTypePtr(ast.String)) {
+		t.Fatalf("Expected conversion to string, got %v", binary.Left)
+	}
+	if _, ok := str.Value.(*ast.Slice); !ok {
+		t.Fatalf("Expected Slice inside Conversion, got %T", str.Value)
+	}
+}
+
+func TestParseBreakContinue(t *testing.T) {
+	input := "int main() { for true { if false { continue; }; break; } }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		tFatalf(t, "Parsing failed: %v", err)
+	}
+
+	loop, ok := program.Declarations[0].Body.ImplicitReturn.(*ast.Loop)
+	if !ok {
+		t.Fatalf("Expected Loop expression, got %T", program.Declarations[0].Body.ImplicitReturn)
+	}
+
+	if len(loop.Body.Body) != 2 {
+		t.Fatalf("Expected 2 statements in loop body, got %d", len(loop.Body.Body))
+	}
+
+	condition, ok := loop.Body.Body[0].(*ast.Condition)
+	if !ok {
+		t.Fatalf("Expected Condition expression, got %T", loop.Body.Body[0])
+	}
+
+	if _, ok := condition.Body.(*ast.Block).Body[0].(*ast.Continue); !ok {
+		t.Fatalf("Expected Continue expression, got %T", condition.Body.(*ast.Block).Body[0])
+	}
+
+	if _, ok := loop.Body.Body[1].(*ast.Break); !ok {
+		t.Fatalf("Expected Break expression, got %T", loop.Body.Body[1])
+	}
+}
+
+func TestParseInvalidAssignment(t *testing.T) {
+	input := "int main() { a + 1 = 2; }"
+	l := lexer.New(lexer.NewSourceFile("test", input))
+	tokens, err := l.Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+
+	p := New(tokens)
+	if _, err := p.Parse(); err == nil {
+		t.Fatalf("Expected parsing to fail on invalid assignment target")
+	}
+}
+
 func TestParseExamples(t *testing.T) {
 	Examples := []string{
 		`
@@ -490,6 +1308,25 @@ func TestParseExamples(t *testing.T) {
 			printf("a = %d\n", a);
 		}
 		`,
+		`
+		struct Inner { int value; }
+		struct Outer { Inner inner; []int values; }
+
+		int main() {
+			let o: Outer = 0;
+			o.inner.value = 1;
+			o.inner.value + 1
+		}
+		`,
+		`
+		[3]int triple(int x) { [x, x * 2, x * 3] }
+		[]int numbers(int n) { make(int, n) }
+		^int first(^int a, ^int b) { a }
+
+		int main() {
+			triple(1)[2] + numbers(3)[0]
+		}
+		`,
 	}
 
 	for i, example := range Examples {
@@ -3,7 +3,7 @@ package type_checker
 import (
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 
 	"github.com/MisustinIvan/ilang/internal/ast"
 	"github.com/MisustinIvan/ilang/internal/lexer"
@@ -21,12 +21,47 @@ type Function struct {
 type Checker struct {
 	prog         *ast.Program
 	declarations map[*ast.Identifier]Function
+	structs      map[*ast.Identifier]*ast.StructDeclaration
+	globals      map[*ast.Identifier]bool
+	constants    map[*ast.Identifier]bool
+	loopDepth    int              // how many loop bodies enclose the checked expression
+	declaration  *ast.Declaration // the function whose body is being checked
+	// what the last value assigned to slice, pointer and string variables
+	// refers to, so that the ones not referring to the heap can't be released
+	refers map[*ast.Identifier]memory
 }
 
+// memory is what a slice, pointer or string value refers to, as far as it can
+// be told at compile time.
+type memory int
+
+const (
+	heapMemory   memory = iota // possibly heap memory, which can be released
+	stackMemory                // an array or memory in a stack frame
+	staticMemory               // a string literal or constant
+	sliceMemory                // a part of the elements of another slice
+)
+
 func NewChecker(prog *ast.Program) *Checker {
 	c := &Checker{
 		prog:         prog,
 		declarations: make(map[*ast.Identifier]Function),
+		structs:      make(map[*ast.Identifier]*ast.StructDeclaration),
+		globals:      make(map[*ast.Identifier]bool),
+		constants:    make(map[*ast.Identifier]bool),
+		refers:       make(map[*ast.Identifier]memory),
+	}
+
+	for _, global := range prog.Globals {
+		c.globals[global.Identifier] = true
+	}
+
+	for _, constant := range prog.Constants {
+		c.constants[constant.Identifier] = true
+	}
+
+	for _, decl := range prog.StructDeclarations {
+		c.structs[decl.Identifier] = decl
 	}
 
 	for _, decl := range prog.Declarations {
@@ -50,10 +85,26 @@ func (c *Checker) CheckTypes() (*ast.Program, error) { return c.prog, c.VisitPro
 func (c *Checker) VisitProgram(p *ast.Program) error {
 	var err error
 
+	for _, decl := range p.StructDeclarations {
+		err = errors.Join(err, decl.Accept(c))
+	}
+
+	for _, decl := range p.EnumDeclarations {
+		err = errors.Join(err, decl.Accept(c))
+	}
+
 	for _, decl := range p.ExternalDeclarations {
 		err = errors.Join(err, decl.Accept(c))
 	}
 
+	for _, constant := range p.Constants {
+		err = errors.Join(err, constant.Accept(c))
+	}
+
+	for _, global := range p.Globals {
+		err = errors.Join(err, global.Accept(c))
+	}
+
 	for _, decl := range p.Declarations {
 		err = errors.Join(err, decl.Accept(c))
 	}
@@ -64,21 +115,161 @@ func (c *Checker) VisitProgram(p *ast.Program) error {
 func (c *Checker) VisitDeclaration(d *ast.Declaration) error {
 	var err error
 
+	switch t := d.Type.(type) {
+	case *ast.BasicType, *ast.StructType, *ast.ArrayType, *ast.PointerType, *ast.EnumType, *ast.FunctionType:
+	case *ast.SliceType:
+		if t.LengthIdentifier != nil {
+			err = errors.Join(err, typeError(d.Identifier.Position, "return type of function %s can't bind a slice length", d.Identifier.Name))
+		}
+	default:
+		err = errors.Join(err, typeError(d.Identifier.Position, "function %s can't return value of type %v", d.Identifier.Name, d.Type))
+	}
+
+	c.declaration = d
 	err = errors.Join(err, d.Body.Accept(c))
-	if !d.Body.GetType().Equals(&d.Type) {
+	if !d.Type.Equals(d.Body.GetType()) {
 		err = errors.Join(err, typeError(d.Body.Position, "body type: %v does not match function type: %v", d.Body.GetType(), d.Type))
+	} else if _, isReturn := d.Body.ImplicitReturn.(*ast.Return); d.Body.ImplicitReturn != nil && !isReturn {
+		err = errors.Join(err, c.checkReturnedArray(d.Body.ImplicitReturn))
 	}
 
 	return err
 }
 
+// checkReturnedArray rejects returning an array as a slice unless the array
+// is an argument or a global, because the slice would point into the
+// returning function's stack frame. Array arguments are passed by reference,
+// so they outlive the call.
+func (c *Checker) checkReturnedArray(v ast.Expression) error {
+	if _, isSlice := c.declaration.Type.(*ast.SliceType); !isSlice {
+		return nil
+	}
+	if slice, ok := v.(*ast.Slice); ok {
+		v = slice.Value // a slice of a local array points into the stack frame too
+	}
+	if _, isArray := v.GetType().(*ast.ArrayType); !isArray {
+		return nil
+	}
+	if identifier, ok := v.(*ast.Identifier); ok {
+		if c.globals[identifier.Resolved] {
+			return nil
+		}
+		for _, arg := range c.declaration.Args {
+			if arg.Identifier == identifier.Resolved {
+				return nil
+			}
+		}
+	}
+	return typeError(v.GetPosition(), "can't return local array of type %v as a slice of type %v", v.GetType(), c.declaration.Type)
+}
+
+func (c *Checker) VisitStructDeclaration(d *ast.StructDeclaration) error {
+	var err error
+
+	for _, field := range d.Fields {
+		if field.Type.Size() == 0 {
+			err = errors.Join(err, typeError(field.Identifier.Position, "field %s of struct %s must have non-zero size", field.Identifier.Name, d.Identifier.Name))
+		}
+		if sliceType, ok := field.Type.(*ast.SliceType); ok && sliceType.LengthIdentifier != nil {
+			err = errors.Join(err, typeError(field.Identifier.Position, "field %s of struct %s can't bind a slice length", field.Identifier.Name, d.Identifier.Name))
+		}
+	}
+
+	return err
+}
+
+func (c *Checker) VisitConstant(cn *ast.Constant) error {
+	err := cn.Value.Accept(c)
+
+	t, isBasic := cn.Type.(*ast.BasicType)
+	_, isEnum := cn.Type.(*ast.EnumType)
+	if !isEnum && (!isBasic || *t == ast.Unit || *t == ast.Undefined) {
+		return errors.Join(err, typeError(cn.Identifier.Position, "constant %s can't be of type %v", cn.Identifier.Name, cn.Type))
+	}
+	if !cn.Type.Equals(cn.Value.GetType()) {
+		err = errors.Join(err, typeError(cn.Value.GetPosition(), "constant value type: %v does not match expected type: %v", cn.Value.GetType(), cn.Type))
+	}
+
+	return err
+}
+
+// VisitGlobal checks the initial value of the global, globals of types other
+// than the basic types and enums can only be zero-initialized.
+func (c *Checker) VisitGlobal(g *ast.Global) error {
+	var err error
+
+	if g.Type.Size() == 0 {
+		err = errors.Join(err, typeError(g.Identifier.Position, "global %s must have non-zero size", g.Identifier.Name))
+	}
+	if sliceType, ok := g.Type.(*ast.SliceType); ok && sliceType.LengthIdentifier != nil {
+		err = errors.Join(err, typeError(g.Identifier.Position, "global %s can't bind a slice length", g.Identifier.Name))
+	}
+
+	_, isBasic := g.Type.(*ast.BasicType)
+	_, isEnum := g.Type.(*ast.EnumType)
+	if !isBasic && !isEnum {
+		if literal, isLiteral := g.Value.(*ast.Literal); !isLiteral || literal.Value != "0" {
+			err = errors.Join(err, typeError(g.Value.GetPosition(), "global %s of type %v can only be zero-initialized with 0", g.Identifier.Name, g.Type))
+		}
+		return err
+	}
+
+	if !g.Type.Equals(g.Value.GetType()) {
+		err = errors.Join(err, typeError(g.Value.GetPosition(), "global value type: %v does not match expected type: %v", g.Value.GetType(), g.Type))
+	}
+	return errors.Join(err, g.Value.Accept(c))
+}
+
 func (c *Checker) VisitExternalDeclaration(d *ast.ExternalDeclaration) error { return nil } // here everything should be fine
 func (c *Checker) VisitArgument(a *ast.Argument) error                       { return nil }
 func (c *Checker) VisitBasicType(t *ast.BasicType) error                     { return nil }
 func (c *Checker) VisitArrayType(t *ast.ArrayType) error                     { return nil }
 func (c *Checker) VisitSliceType(t *ast.SliceType) error                     { return nil }
 func (c *Checker) VisitPointerType(t *ast.PointerType) error                 { return nil }
-func (c *Checker) VisitReturn(e *ast.Return) error                           { return e.Value.Accept(c) }
+func (c *Checker) VisitStructType(t *ast.StructType) error                   { return nil }
+func (c *Checker) VisitEnumType(t *ast.EnumType) error                       { return nil }
+func (c *Checker) VisitFunctionType(t *ast.FunctionType) error               { return nil }
+func (c *Checker) VisitVariantAccess(v *ast.VariantAccess) error             { return nil }
+
+// VisitEnumDeclaration checks that the variants have distinct numbers, so
+// that they can be told apart.
+func (c *Checker) VisitEnumDeclaration(d *ast.EnumDeclaration) error {
+	var err error
+
+	numbers := map[int64]*ast.EnumVariant{}
+	for i, variant := range d.Variants {
+		if other, exists := numbers[variant.Number]; exists {
+			err = errors.Join(err, typeError(variant.Identifier.Position, "variant %s has the same value %d as variant %s", variant.Identifier.Name, variant.Number, other.Identifier.Name))
+			continue
+		}
+		numbers[variant.Number] = &d.Variants[i]
+	}
+
+	return err
+}
+
+func (c *Checker) VisitReturn(e *ast.Return) error {
+	err := e.Value.Accept(c)
+	if !c.declaration.Type.Equals(e.Value.GetType()) {
+		return errors.Join(err, typeError(e.Position, "returned value type: %v does not match function type: %v", e.Value.GetType(), c.declaration.Type))
+	}
+	return errors.Join(err, c.checkReturnedArray(e.Value))
+}
+
+func (c *Checker) VisitBreak(b *ast.Break) error {
+	if c.loopDepth == 0 {
+		return typeError(b.Position, "break outside of a loop")
+	}
+	return nil
+}
+
+func (c *Checker) VisitContinue(cn *ast.Continue) error {
+	if c.loopDepth == 0 {
+		return typeError(cn.Position, "continue outside of a loop")
+	}
+	return nil
+}
+
 func (c *Checker) VisitBind(b *ast.Bind) error {
 	var err error
 
@@ -86,8 +277,10 @@ func (c *Checker) VisitBind(b *ast.Bind) error {
 		err = errors.Join(err, typeError(b.Position, "bound value must have non-zero size"))
 	}
 
-	// array zero-intialization
-	if _, isArray := b.Type.(*ast.ArrayType); isArray {
+	// array and struct zero-intialization
+	_, isArray := b.Type.(*ast.ArrayType)
+	_, isStruct := b.Type.(*ast.StructType)
+	if isArray || isStruct {
 		if literal, isLiteral := b.Value.(*ast.Literal); isLiteral {
 			if literal.Value == "0" {
 				return nil
@@ -99,18 +292,42 @@ func (c *Checker) VisitBind(b *ast.Bind) error {
 		err = errors.Join(err, typeError(b.Position, "bound value type: %v does not match expected type: %v", b.Value.GetType(), b.Type))
 	}
 	err = errors.Join(err, b.Value.Accept(c))
+	c.refers[b.Identifier] = c.refersTo(b.Value)
 
 	return err
 }
 
-func (c *Checker) VisitLiteral(l *ast.Literal) error       { return nil }
-func (c *Checker) VisitIdentifier(i *ast.Identifier) error { return nil }
+func (c *Checker) VisitLiteral(l *ast.Literal) error { return nil }
+func (c *Checker) VisitIdentifier(i *ast.Identifier) error {
+	if _, isStruct := c.structs[i.Resolved]; isStruct {
+		return typeError(i.Position, "struct type %s can't be used as a value", i.Name)
+	}
+	if function, isFunction := c.declarations[i.Resolved]; isFunction && function.Variadic {
+		return typeError(i.Position, "variadic function %s can only be called directly", i.Name)
+	}
+	return nil
+}
 func (c *Checker) VisitCall(cl *ast.Call) error {
 	var err error
 
-	function, ok := c.declarations[cl.Identifier.Resolved]
-	if !ok {
-		return typeError(cl.Position, "calling unresolved function %s", cl.Identifier.Name)
+	var function Function
+	isDeclared := false
+	if identifier, ok := cl.Function.(*ast.Identifier); ok {
+		function, isDeclared = c.declarations[identifier.Resolved]
+	}
+	if !isDeclared {
+		// calls through a function pointer are checked against its type
+		if err := cl.Function.Accept(c); err != nil {
+			return err
+		}
+		functionType, isFunction := cl.Function.GetType().(*ast.FunctionType)
+		if !isFunction {
+			return typeError(cl.Position, "can't call value of type %v", cl.Function.GetType())
+		}
+		function = Function{Variadic: functionType.Variadic}
+		for _, parameter := range functionType.Parameters {
+			function.Args = append(function.Args, ast.Argument{Type: parameter})
+		}
 	}
 
 	declared_args := function.Args
@@ -148,6 +365,31 @@ func (c *Checker) VisitUnary(u *ast.Unary) error {
 	var err error
 
 	err = errors.Join(err, u.Value.Accept(c))
+
+	// the address can be taken of a value of any type, as long as it lives somewhere
+	if u.Operator == ast.AddressOf {
+		if u.Value.GetType().Equals(ast.BasicTypePtr(ast.Unit)) {
+			err = errors.Join(err, typeError(u.Position, "can't take address of value of type unit"))
+		}
+		if identifier, ok := u.Value.(*ast.Identifier); ok && c.constants[identifier.Resolved] {
+			return errors.Join(err, typeError(u.Position, "can't take address of constant %s", identifier.Name))
+		}
+		if identifier, ok := u.Value.(*ast.Identifier); ok {
+			if _, isFunction := c.declarations[identifier.Resolved]; isFunction {
+				return errors.Join(err, typeError(u.Position, "can't take address of function %s", identifier.Name))
+			}
+		}
+		if isStringByte(u.Value) {
+			return errors.Join(err, typeError(u.Position, "can't take address of byte of string, strings are immutable"))
+		}
+		switch u.Value.(type) {
+		case *ast.Identifier, *ast.Index, *ast.FieldAccess, *ast.Dereference:
+		default:
+			err = errors.Join(err, typeError(u.Position, "can only take address of identifiers, indexes, field accesses and dereferences"))
+		}
+		return err
+	}
+
 	t, ok := u.Value.GetType().(*ast.BasicType)
 	if t == nil || !ok {
 		return typeError(u.Position, "unary expression value does is not basic type: %s", u.GetType().String())
@@ -156,12 +398,6 @@ func (c *Checker) VisitUnary(u *ast.Unary) error {
 		err = errors.Join(err, typeError(u.Position, "unary operator does not apply to type %v", u.Value.GetType()))
 	}
 
-	if u.Operator == ast.AddressOf {
-		if _, ok := u.Value.(*ast.Identifier); !ok {
-			err = errors.Join(err, typeError(u.Position, "can only take address of identifiers"))
-		}
-	}
-
 	return err
 }
 
@@ -171,8 +407,19 @@ func (c *Checker) VisitBinary(u *ast.Binary) error {
 	err = errors.Join(err, u.Left.Accept(c))
 	err = errors.Join(err, u.Right.Accept(c))
 
+	if _, ok := u.Left.GetType().(*ast.PointerType); ok {
+		return errors.Join(err, c.checkPointerBinary(u))
+	}
+	if _, ok := u.Left.GetType().(*ast.EnumType); ok {
+		return errors.Join(err, c.checkEnumBinary(u))
+	}
+
 	if !u.Left.GetType().Equals(u.Right.GetType()) {
-		err = errors.Join(err, typeError(u.Position, "binary expression types dont match - %v vs %v", u.Left.GetType(), u.Right.GetType()))
+		if isNumeric(u.Left.GetType()) && isNumeric(u.Right.GetType()) {
+			err = errors.Join(err, typeError(u.Position, "binary expression types dont match - %v vs %v, convert one of them with int() or float()", u.Left.GetType(), u.Right.GetType()))
+		} else {
+			err = errors.Join(err, typeError(u.Position, "binary expression types dont match - %v vs %v", u.Left.GetType(), u.Right.GetType()))
+		}
 	}
 	t, ok := u.Left.GetType().(*ast.BasicType)
 	if t == nil || !ok {
@@ -185,6 +432,42 @@ func (c *Checker) VisitBinary(u *ast.Binary) error {
 	return err
 }
 
+// checkPointerBinary checks a binary expression with a pointer on the left.
+// An int can be added to or subtracted from a pointer, and pointers of the
+// same type can be compared.
+func (c *Checker) checkPointerBinary(u *ast.Binary) error {
+	switch u.Operator {
+	case ast.Addition, ast.Subtraction:
+		if !u.Right.GetType().Equals(ast.BasicTypePtr(ast.Int)) {
+			return typeError(u.Position, "pointer offset must be of type int, got %v", u.Right.GetType())
+		}
+	case ast.Equality, ast.Inequality, ast.Less, ast.Greater, ast.LessEqual, ast.GreaterEqual:
+		if !u.Left.GetType().Equals(u.Right.GetType()) {
+			return typeError(u.Position, "binary expression types dont match - %v vs %v", u.Left.GetType(), u.Right.GetType())
+		}
+	default:
+		return typeError(u.Position, "binary operator %v does not apply to type %v", u.Operator.String(), u.Left.GetType())
+	}
+	return nil
+}
+
+// checkEnumBinary checks a binary expression with an enum on the left, enums
+// of the same type can only be compared for equality.
+func (c *Checker) checkEnumBinary(u *ast.Binary) error {
+	if u.Operator != ast.Equality && u.Operator != ast.Inequality {
+		return typeError(u.Position, "binary operator %v does not apply to type %v", u.Operator.String(), u.Left.GetType())
+	}
+	if !u.Left.GetType().Equals(u.Right.GetType()) {
+		return typeError(u.Position, "binary expression types dont match - %v vs %v", u.Left.GetType(), u.Right.GetType())
+	}
+	return nil
+}
+
+// isNumeric reports whether t is an integer type or float.
+func isNumeric(t ast.Type) bool {
+	return ast.IsInteger(t) || t.Equals(ast.BasicTypePtr(ast.Float))
+}
+
 func (c *Checker) VisitBlock(b *ast.Block) error {
 	var err error
 
@@ -228,19 +511,44 @@ func (c *Checker) VisitAssignment(a *ast.Assignment) error {
 	if !a.Value.GetType().Equals(a.Target.GetType()) {
 		err = errors.Join(err, typeError(a.GetPosition(), "assigning value of type %v to target of type %v", a.Value.GetType(), a.Target.GetType()))
 	}
+	if isStringByte(a.Target) {
+		err = errors.Join(err, typeError(a.GetPosition(), "can't assign to byte of string, strings are immutable"))
+	}
+	if identifier, ok := a.Target.(*ast.Identifier); ok {
+		if c.constants[identifier.Resolved] {
+			err = errors.Join(err, typeError(a.GetPosition(), "can't assign to constant %s", identifier.Name))
+		}
+		if _, isFunction := c.declarations[identifier.Resolved]; isFunction {
+			err = errors.Join(err, typeError(a.GetPosition(), "can't assign to function %s", identifier.Name))
+		}
+		c.refers[identifier.Resolved] = c.refersTo(a.Value)
+	}
 
 	return err
 }
 
+// isStringByte reports whether the value indexes a string. The bytes of
+// string literals are shared by all of their uses, so they can't be modified.
+func isStringByte(v ast.Value) bool {
+	index, ok := v.(*ast.Index)
+	return ok && index.Value.GetType().Equals(ast.BasicTypePtr(ast.String))
+}
+
 func (c *Checker) VisitDereference(d *ast.Dereference) error {
+	err := d.Value.Accept(c)
 	if _, ok := d.Value.GetType().(*ast.PointerType); !ok {
-		return typeError(d.GetPosition(), "can only dereference pointer types, got %s", d.Value.GetType())
+		return errors.Join(err, typeError(d.GetPosition(), "can only dereference pointer types, got %s", d.Value.GetType()))
 	}
-	return nil
+	return err
 }
 
 func (c *Checker) VisitLoop(l *ast.Loop) error {
-	err := errors.Join(l.Condition.Accept(c), l.Body.Accept(c))
+	err := l.Condition.Accept(c)
+
+	c.loopDepth++
+	err = errors.Join(err, l.Body.Accept(c))
+	c.loopDepth--
+
 	if !l.Condition.GetType().Equals(ast.BasicTypePtr(ast.Bool)) {
 		err = errors.Join(typeError(l.Condition.GetPosition(), "condition must be of type bool, got %s", l.Condition.GetType().String()))
 	}
@@ -248,25 +556,190 @@ func (c *Checker) VisitLoop(l *ast.Loop) error {
 }
 
 func (c *Checker) VisitMake(m *ast.Make) error {
+	if m.Length == nil {
+		if _, isPointer := m.Type.(*ast.PointerType); !isPointer {
+			return typeError(m.Position, "make without a length needs a pointer type, got %v", m.Type)
+		}
+		return nil
+	}
+
 	err := m.Length.Accept(c)
 
+	if m.Type.Size() == 0 {
+		err = errors.Join(err, typeError(m.Position, "can't make slice of elements of type %v with zero size", m.Type))
+	}
 	if !m.Length.GetType().Equals(ast.BasicTypePtr(ast.Int)) {
-		err = errors.Join(typeError(m.Length.GetPosition(), "length value must be of type int in make expression"))
+		err = errors.Join(err, typeError(m.Length.GetPosition(), "length value must be of type int in make expression"))
 	}
 
 	return err
 }
 
-func (c *Checker) VisitRelease(m *ast.Release) error {
-	err := m.Value.Accept(c)
+func (c *Checker) VisitConversion(cv *ast.Conversion) error {
+	err := cv.Value.Accept(c)
+
+	// enums convert to the number of their variant
+	if _, isEnum := cv.Value.GetType().(*ast.EnumType); isEnum && ast.IsInteger(cv.Type) {
+		return err
+	}
+	// strings convert to a slice of their bytes, and bytes back to strings
+	if _, isSlice := cv.Type.(*ast.SliceType); isSlice && isBytes(cv.Type) && cv.Value.GetType().Equals(ast.BasicTypePtr(ast.String)) {
+		return err
+	}
+	if cv.Type.Equals(ast.BasicTypePtr(ast.String)) && isBytes(cv.Value.GetType()) {
+		return err
+	}
 
-	if _, isSliceType := m.Value.GetType().(*ast.SliceType); !isSliceType {
-		err = errors.Join(typeError(m.Value.GetPosition(), "can only release value of slice type"))
+	to, isBasic := cv.Type.(*ast.BasicType)
+	from, ok := cv.Value.GetType().(*ast.BasicType)
+	if !isBasic || !ok || !ast.ConversionApplies[*to][*from] {
+		err = errors.Join(err, typeError(cv.Position, "can't convert value of type %v to %v", cv.Value.GetType(), cv.Type))
 	}
 
 	return err
 }
 
+func (c *Checker) VisitLength(l *ast.Length) error {
+	err := l.Value.Accept(c)
+
+	switch l.Value.GetType().(type) {
+	case *ast.ArrayType, *ast.SliceType:
+	default:
+		if !l.Value.GetType().Equals(ast.BasicTypePtr(ast.String)) {
+			err = errors.Join(err, typeError(l.Position, "can't take length of value of type %v, only of arrays, slices and strings", l.Value.GetType()))
+		}
+	}
+
+	return err
+}
+
+func (c *Checker) VisitAppend(a *ast.Append) error {
+	err := errors.Join(a.Slice.Accept(c), a.Value.Accept(c))
+
+	if sliceType, ok := a.GetType().(*ast.SliceType); ok && !sliceType.Element.Equals(a.Value.GetType()) {
+		err = errors.Join(err, typeError(a.Value.GetPosition(), "appending value of type %v to slice of type %v", a.Value.GetType(), a.GetType()))
+	}
+
+	return err
+}
+
+func (c *Checker) VisitCopy(cp *ast.Copy) error {
+	err := errors.Join(cp.Destination.Accept(c), cp.Source.Accept(c))
+
+	destination, isContainer := elementType(cp.Destination.GetType())
+	if !isContainer {
+		return errors.Join(err, typeError(cp.Destination.GetPosition(), "can only copy to arrays and slices, got %v", cp.Destination.GetType()))
+	}
+	source, isContainer := elementType(cp.Source.GetType())
+	if !isContainer {
+		return errors.Join(err, typeError(cp.Source.GetPosition(), "can only copy from arrays and slices, got %v", cp.Source.GetType()))
+	}
+	if !ast.Identical(destination, source) {
+		err = errors.Join(err, typeError(cp.Position, "copying elements of type %v to elements of type %v", source, destination))
+	}
+
+	return err
+}
+
+// elementType returns the element type of an array or slice type.
+func elementType(t ast.Type) (ast.Type, bool) {
+	switch t := t.(type) {
+	case *ast.ArrayType:
+		return t.Element, true
+	case *ast.SliceType:
+		return t.Element, true
+	}
+	return nil, false
+}
+
+// isBytes reports whether t is an array or slice of u8, which can be
+// converted to a string.
+func isBytes(t ast.Type) bool {
+	element, isContainer := elementType(t)
+	return isContainer && element.Equals(ast.BasicTypePtr(ast.U8))
+}
+
+func (c *Checker) VisitRelease(r *ast.Release) error {
+	err := r.Value.Accept(c)
+
+	switch r.Value.GetType().(type) {
+	case *ast.SliceType, *ast.PointerType:
+	case *ast.ArrayType:
+		return errors.Join(err, typeError(r.Value.GetPosition(), "can't release array of type %v, arrays are stored on the stack", r.Value.GetType()))
+	default:
+		// strings built at runtime live on the heap, literals don't
+		if !r.Value.GetType().Equals(ast.BasicTypePtr(ast.String)) {
+			return errors.Join(err, typeError(r.Value.GetPosition(), "can only release values of slice, pointer or string type, got %v", r.Value.GetType()))
+		}
+		if _, isLiteral := r.Value.(*ast.Literal); isLiteral {
+			return errors.Join(err, typeError(r.Value.GetPosition(), "can't release string literal"))
+		}
+	}
+	switch c.refersTo(r.Value) {
+	case stackMemory:
+		err = errors.Join(err, typeError(r.Value.GetPosition(), "can't release value of type %v, it refers to stack memory", r.Value.GetType()))
+	case staticMemory:
+		err = errors.Join(err, typeError(r.Value.GetPosition(), "can't release value of type %v, it refers to a string literal or constant", r.Value.GetType()))
+	case sliceMemory:
+		err = errors.Join(err, typeError(r.Value.GetPosition(), "can't release value of type %v, its elements belong to the value it was sliced from", r.Value.GetType()))
+	}
+
+	return err
+}
+
+// refersTo tells what memory the value refers to, as far as it can be told
+// from the value alone and the values last assigned to slice, pointer and
+// string variables.
+func (c *Checker) refersTo(v ast.Value) memory {
+	if _, isArray := v.GetType().(*ast.ArrayType); isArray {
+		return stackMemory
+	}
+
+	switch v := v.(type) {
+	case *ast.Separated:
+		return c.refersTo(v.Value)
+	case *ast.Literal:
+		if v.GetType().Equals(ast.BasicTypePtr(ast.String)) {
+			return staticMemory
+		}
+	case *ast.Identifier:
+		if c.constants[v.Resolved] && v.GetType().Equals(ast.BasicTypePtr(ast.String)) {
+			return staticMemory
+		}
+		return c.refers[v.Resolved]
+	case *ast.Slice:
+		if inner := c.refersTo(v.Value); inner != heapMemory {
+			return inner
+		}
+		return sliceMemory
+	case *ast.Unary:
+		if v.Operator == ast.AddressOf && c.storedOnStack(v.Value) {
+			return stackMemory
+		}
+	}
+	return heapMemory
+}
+
+// storedOnStack reports whether the addressable value lives in a stack frame.
+func (c *Checker) storedOnStack(v ast.Value) bool {
+	switch v := v.(type) {
+	case *ast.Separated:
+		return c.storedOnStack(v.Value)
+	case *ast.Identifier:
+		return true
+	case *ast.Index:
+		if _, isArray := v.Value.GetType().(*ast.ArrayType); isArray {
+			return c.storedOnStack(v.Value)
+		}
+		return c.refersTo(v.Value) == stackMemory
+	case *ast.FieldAccess:
+		return c.storedOnStack(v.Value)
+	case *ast.Dereference:
+		return c.refersTo(v.Value) == stackMemory
+	}
+	return false
+}
+
 func (c *Checker) VisitArrayLiteral(a *ast.ArrayLiteral) error {
 	var err error
 
@@ -291,21 +764,17 @@ func (c *Checker) VisitArrayLiteral(a *ast.ArrayLiteral) error {
 
 func (c *Checker) VisitIndex(i *ast.Index) error {
 	var err error
-	err = errors.Join(err, i.Index.Accept(c))
+	err = errors.Join(err, i.Value.Accept(c), i.Index.Accept(c))
 
-	if i.Identifier.Resolved == nil {
-		return errors.Join(err, typeError(i.Position, "indexing unresolved identifier"))
-	}
-
-	if !i.Index.GetType().Equals(ast.BasicTypePtr(ast.Int)) {
+	if !ast.IsInteger(i.Index.GetType()) {
 		err = errors.Join(err, typeError(i.Index.GetPosition(), "can't index array with non-integer value"))
 	}
 
 	if literal, isLiteral := i.Index.(*ast.Literal); isLiteral {
-		intVal, parseErr := strconv.Atoi(literal.Value)
+		intVal, parseErr := lexer.ParseIntLiteral(literal.Value)
 		if parseErr == nil {
-			if arrayType, ok := i.Identifier.Resolved.GetType().(*ast.ArrayType); ok {
-				if intVal < 0 || intVal >= arrayType.Length {
+			if arrayType, ok := i.Value.GetType().(*ast.ArrayType); ok {
+				if intVal < 0 || intVal >= int64(arrayType.Length) {
 					err = errors.Join(err, typeError(i.Position, "array index %d out of bounds (size %d)", intVal, arrayType.Length))
 				}
 			}
@@ -315,3 +784,109 @@ func (c *Checker) VisitIndex(i *ast.Index) error {
 
 	return err
 }
+
+func (c *Checker) VisitSlice(s *ast.Slice) error {
+	err := s.Value.Accept(c)
+
+	bounds := []ast.Value{}
+	if s.Low != nil {
+		bounds = append(bounds, s.Low)
+	}
+	if s.High != nil {
+		bounds = append(bounds, s.High)
+	}
+	for _, bound := range bounds {
+		err = errors.Join(err, bound.Accept(c))
+		if !ast.IsInteger(bound.GetType()) {
+			err = errors.Join(err, typeError(bound.GetPosition(), "can't slice with non-integer bound"))
+		}
+	}
+
+	// constant bounds are checked right away
+	low, lowConstant := int64(0), true
+	if s.Low != nil {
+		low, lowConstant = constantInt(s.Low)
+	}
+	high, highConstant := int64(0), false
+	if s.High != nil {
+		high, highConstant = constantInt(s.High)
+	}
+	if arrayType, ok := s.Value.GetType().(*ast.ArrayType); ok {
+		length := int64(arrayType.Length)
+		if !highConstant && s.High == nil {
+			high, highConstant = length, true
+		}
+		if highConstant && high > length {
+			err = errors.Join(err, typeError(s.Position, "slice bound %d out of bounds (size %d)", high, arrayType.Length))
+		}
+	}
+	if lowConstant && highConstant && low > high {
+		err = errors.Join(err, typeError(s.Position, "invalid slice bounds [%d:%d]", low, high))
+	}
+
+	return err
+}
+
+// constantInt returns the value of an integer literal.
+func constantInt(v ast.Value) (int64, bool) {
+	literal, isLiteral := v.(*ast.Literal)
+	if !isLiteral {
+		return 0, false
+	}
+	value, err := lexer.ParseIntLiteral(literal.Value)
+	return value, err == nil
+}
+
+func (c *Checker) VisitFieldAccess(f *ast.FieldAccess) error { return f.Value.Accept(c) }
+
+// VisitMatch checks that the patterns have the type of the matched value and
+// that every case is matched once. Matches on enums without an else arm must
+// match all the variants, other matches without one can't result in a value.
+func (c *Checker) VisitMatch(m *ast.Match) error {
+	err := m.Value.Accept(c)
+
+	t := m.Value.GetType()
+	_, isEnum := t.(*ast.EnumType)
+	if !isEnum && !ast.IsInteger(t) {
+		err = errors.Join(err, typeError(m.Value.GetPosition(), "can't match value of type %v, only integers and enums can be matched", t))
+	}
+
+	matched := map[int64]bool{}
+	for _, arm := range m.Arms {
+		for i, pattern := range arm.Patterns {
+			err = errors.Join(err, pattern.Accept(c))
+			if !pattern.GetType().Equals(t) {
+				err = errors.Join(err, typeError(pattern.GetPosition(), "pattern of type %v does not match value of type %v", pattern.GetType(), t))
+			} else if matched[arm.Cases[i]] {
+				err = errors.Join(err, typeError(pattern.GetPosition(), "pattern is already matched by an earlier arm"))
+			}
+			matched[arm.Cases[i]] = true
+		}
+
+		err = errors.Join(err, arm.Body.Accept(c))
+		if !arm.Body.GetType().Equals(m.GetType()) {
+			err = errors.Join(err, typeError(arm.Body.GetPosition(), "arms of match dont have the same type - %v vs %v", m.GetType(), arm.Body.GetType()))
+		}
+	}
+
+	if m.Else != nil {
+		err = errors.Join(err, m.Else.Accept(c))
+		if !m.Else.GetType().Equals(m.GetType()) {
+			err = errors.Join(err, typeError(m.Else.GetPosition(), "arms of match dont have the same type - %v vs %v", m.GetType(), m.Else.GetType()))
+		}
+	}
+
+	if isEnum && !m.Exhaustive() {
+		var missing []string
+		for _, variant := range t.(*ast.EnumType).Declaration.Variants {
+			if !matched[variant.Number] {
+				missing = append(missing, variant.Identifier.Name)
+			}
+		}
+		err = errors.Join(err, typeError(m.Position, "match on %v is not exhaustive, missing %s", t, strings.Join(missing, ", ")))
+	} else if !m.Exhaustive() && !m.GetType().Equals(ast.BasicTypePtr(ast.Unit)) {
+		err = errors.Join(err, typeError(m.Position, "match without an else arm can't result in a value of type %v", m.GetType()))
+	}
+
+	return err
+}
@@ -0,0 +1,89 @@
+package type_checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MisustinIvan/ilang/internal/lexer"
+	"github.com/MisustinIvan/ilang/internal/name_resolver"
+	"github.com/MisustinIvan/ilang/internal/parser"
+	"github.com/MisustinIvan/ilang/internal/type_resolver"
+)
+
+// check runs the passes before the type checker on the input and then the
+// type checker itself, returning its error.
+func check(t *testing.T, input string) error {
+	t.Helper()
+	tokens, err := lexer.New(lexer.NewSourceFile("test", input)).Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+	program, err := parser.New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parsing failed: %v", err)
+	}
+	if program, err = name_resolver.NewResolver(program).ResolveNames(); err != nil {
+		t.Fatalf("Name resolution failed: %v", err)
+	}
+	if program, err = type_resolver.NewResolver(program).ResolveTypes(); err != nil {
+		t.Fatalf("Type resolution failed: %v", err)
+	}
+
+	_, err = NewChecker(program).CheckTypes()
+	return err
+}
+
+func TestCheckIndirectCalls(t *testing.T) {
+	functions := "int add(int a, int b) { a + b } int sub(int a, int b) { a - b } fn(int, int) int pick(bool c) { if c { add } else { sub } } "
+	tests := []struct {
+		name          string
+		input         string
+		expectedError string // a part of the error, empty if there is none
+	}{
+		{
+			name:  "IndexedCall",
+			input: "int main() { let ops: [2]fn(int, int) int = [add, sub]; let i: int = 1; ops[i](7, 3) }",
+		},
+		{
+			name:  "ChainedCall",
+			input: "int main() { pick(true)(7, 3) }",
+		},
+		{
+			name:  "CallOfField",
+			input: "struct Op { fn(int, int) int f; } int main() { let op: Op = 0; op.f = add; op.f(7, 3) }",
+		},
+		{
+			name:          "IndexedCallWithWrongArgument",
+			input:         "int main() { let ops: [2]fn(int, int) int = [add, sub]; ops[0](true, 3) }",
+			expectedError: "argument types dont match",
+		},
+		{
+			name:          "ChainedCallWithMissingArgument",
+			input:         "int main() { pick(true)(7) }",
+			expectedError: "missing function call argument",
+		},
+		{
+			name:          "ChainedCallWithWrongCalledArgument",
+			input:         "int main() { pick(1)(7, 3) }",
+			expectedError: "argument types dont match",
+		},
+		{
+			name:          "IndexedCallWithUnexpectedArgument",
+			input:         "int main() { let ops: [2]fn(int, int) int = [add, sub]; ops[0](7, 3, 1) }",
+			expectedError: "unexpected function call argument 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(t, functions+tt.input)
+
+			if tt.expectedError == "" && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.expectedError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectedError)) {
+				t.Fatalf("expected error %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package type_resolver
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/MisustinIvan/ilang/internal/ast"
+	"github.com/MisustinIvan/ilang/internal/lexer"
+)
+
+// fold computes a constant value at compile time, resulting in a literal of
+// the same type. Constant values are made of literals, constants, unary and
+// binary operators and conversions. The types of the value must already be
+// resolved.
+func (r *Resolver) fold(v ast.Value) (*ast.Literal, error) {
+	value, err := r.evaluate(v)
+	if err != nil {
+		return nil, err
+	}
+
+	literal := &ast.Literal{}
+	literal.SetPosition(v.GetPosition())
+	switch value := value.(type) {
+	case int64:
+		literal.Value = strconv.FormatInt(value, 10)
+		literal.SetType(ast.BasicTypePtr(ast.Int))
+		if ast.IsInteger(v.GetType()) {
+			literal.SetType(v.GetType())
+		}
+	case float64:
+		literal.Value = strconv.FormatFloat(value, 'g', -1, 64)
+		literal.SetType(ast.BasicTypePtr(ast.Float))
+	case bool:
+		literal.Value = strconv.FormatBool(value)
+		literal.SetType(ast.BasicTypePtr(ast.Bool))
+	case *ast.Literal: // strings are only ever passed around whole
+		literal.Value = value.Value
+		literal.SetType(value.GetType())
+	}
+	return literal, nil
+}
+
+// evaluate computes the value of a constant value as an int64, float64, bool
+// or a string *ast.Literal. Enum variants are computed as their number.
+func (r *Resolver) evaluate(v ast.Value) (any, error) {
+	switch v := v.(type) {
+	case *ast.Literal:
+		if ast.IsInteger(v.GetType()) {
+			return lexer.ParseUnsignedLiteral(v.Value)
+		}
+		switch *v.GetType().(*ast.BasicType) {
+		case ast.Float:
+			return lexer.ParseFloatLiteral(v.Value)
+		case ast.Bool:
+			return v.Value == "true", nil
+		case ast.String:
+			return v, nil
+		}
+	case *ast.Identifier:
+		if constant, ok := r.constants[v.Resolved]; ok && constant.Folded != nil {
+			return r.evaluate(constant.Folded)
+		}
+	case *ast.VariantAccess:
+		if decl, ok := r.enums[v.Enum.Resolved]; ok {
+			if variant := decl.Variant(v.Variant.Name); variant != nil {
+				return variant.Number, nil
+			}
+		}
+	case *ast.Separated:
+		return r.evaluate(v.Value)
+	case *ast.Unary:
+		value, err := r.evaluate(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		if result, ok := foldUnary(v.Operator, value, integerType(v.Value.GetType())); ok {
+			return result, nil
+		}
+	case *ast.Binary:
+		left, err := r.evaluate(v.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := r.evaluate(v.Right)
+		if err != nil {
+			return nil, err
+		}
+		if (v.Operator == ast.Division || v.Operator == ast.Modulo) && right == int64(0) {
+			return nil, typeResolutionError(v.Right.GetPosition(), "constant division by zero")
+		}
+		if result, ok := foldBinary(v.Operator, left, right, integerType(v.Left.GetType())); ok {
+			return result, nil
+		}
+	case *ast.Conversion:
+		value, err := r.evaluate(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		if t, isBasic := v.Type.(*ast.BasicType); isBasic {
+			if result, ok := foldConversion(*t, value); ok {
+				return result, nil
+			}
+		}
+	}
+	return nil, typeResolutionError(v.GetPosition(), "value is not a constant")
+}
+
+// integerType returns the integer type t, or int for the other types, like
+// enums, whose values are folded as ints.
+func integerType(t ast.Type) ast.BasicType {
+	if basic, ok := t.(*ast.BasicType); ok && ast.IsInteger(basic) {
+		return *basic
+	}
+	return ast.Int
+}
+
+// foldUnary computes the unary operator on a value of type t, wrapping
+// integers around to the range of t.
+func foldUnary(operator ast.UnaryOperator, value any, t ast.BasicType) (any, bool) {
+	switch value := value.(type) {
+	case int64:
+		switch operator {
+		case ast.Inversion:
+			return wrap(-value, t), true
+		case ast.BitNot:
+			return wrap(^value, t), true
+		}
+	case float64:
+		if operator == ast.Inversion {
+			return -value, true
+		}
+	case bool:
+		if operator == ast.LogicNegation {
+			return !value, true
+		}
+	}
+	return nil, false
+}
+
+// foldBinary computes the binary operator on operands of type t the same way
+// the generated code does, so integers wrap around to the range of t, shift
+// counts are taken modulo 64 and unsigned integers are divided, shifted and
+// compared as unsigned.
+func foldBinary(operator ast.BinaryOperator, left, right any, t ast.BasicType) (any, bool) {
+	switch l := left.(type) {
+	case int64:
+		r, ok := right.(int64)
+		if !ok {
+			return nil, false
+		}
+		if result, ok := foldInteger(operator, l, r, t); ok {
+			return wrap(result, t), true
+		}
+		if ast.IsUnsigned(&t) {
+			return compare(operator, uint64(l), uint64(r))
+		}
+		return compare(operator, l, r)
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, false
+		}
+		switch operator {
+		case ast.Addition:
+			return l + r, true
+		case ast.Subtraction:
+			return l - r, true
+		case ast.Multiplication:
+			return l * r, true
+		case ast.Division:
+			return l / r, true
+		}
+		return compare(operator, l, r)
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return nil, false
+		}
+		switch operator {
+		case ast.LogicAnd:
+			return l && r, true
+		case ast.LogicOr:
+			return l || r, true
+		case ast.Equality:
+			return l == r, true
+		case ast.Inequality:
+			return l != r, true
+		}
+	}
+	return nil, false
+}
+
+// foldInteger computes the arithmetic operator on integers of type t, leaving
+// the result to be wrapped around to the range of t.
+func foldInteger(operator ast.BinaryOperator, l, r int64, t ast.BasicType) (int64, bool) {
+	if ast.IsUnsigned(&t) {
+		switch operator {
+		case ast.Division:
+			return int64(uint64(l) / uint64(r)), true
+		case ast.Modulo:
+			return int64(uint64(l) % uint64(r)), true
+		case ast.ShiftRight:
+			return int64(uint64(l) >> (r & 63)), true
+		}
+	}
+	switch operator {
+	case ast.Addition:
+		return l + r, true
+	case ast.Subtraction:
+		return l - r, true
+	case ast.Multiplication:
+		return l * r, true
+	case ast.Division:
+		return l / r, true
+	case ast.Modulo:
+		return l % r, true
+	case ast.ShiftLeft:
+		return l << (r & 63), true
+	case ast.ShiftRight:
+		return l >> (r & 63), true
+	case ast.BitAnd:
+		return l & r, true
+	case ast.BitOr:
+		return l | r, true
+	case ast.BitXor:
+		return l ^ r, true
+	}
+	return 0, false
+}
+
+func compare[T int64 | uint64 | float64](operator ast.BinaryOperator, l, r T) (any, bool) {
+	switch operator {
+	case ast.Equality:
+		return l == r, true
+	case ast.Inequality:
+		return l != r, true
+	case ast.Less:
+		return l < r, true
+	case ast.Greater:
+		return l > r, true
+	case ast.LessEqual:
+		return l <= r, true
+	case ast.GreaterEqual:
+		return l >= r, true
+	}
+	return nil, false
+}
+
+// foldConversion converts the value like ast.Conversion, floats are truncated
+// towards zero, integers wrap around to the range of the target type and are
+// true unless they are zero.
+func foldConversion(t ast.BasicType, value any) (any, bool) {
+	if ast.IsInteger(&t) {
+		switch value := value.(type) {
+		case int64:
+			return wrap(value, t), true
+		case float64:
+			return wrap(int64(value), t), true
+		case bool:
+			if value {
+				return int64(1), true
+			}
+			return int64(0), true
+		}
+		return nil, false
+	}
+	switch t {
+	case ast.Float:
+		switch value := value.(type) {
+		case int64:
+			return float64(value), true
+		case float64:
+			return value, true
+		}
+	case ast.Bool:
+		switch value := value.(type) {
+		case int64:
+			return value != 0, true
+		case bool:
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// wrap truncates the value to the size of the integer type t, extending it
+// back to 64 bits by the signedness of t like the generated code does.
+func wrap(value int64, t ast.BasicType) int64 {
+	switch t {
+	case ast.I8:
+		return int64(int8(value))
+	case ast.I16:
+		return int64(int16(value))
+	case ast.I32:
+		return int64(int32(value))
+	case ast.U8:
+		return int64(uint8(value))
+	case ast.U16:
+		return int64(uint16(value))
+	case ast.U32:
+		return int64(uint32(value))
+	}
+	return value
+}
+
+// adapt gives a constant int value the integer type t expected by the context
+// of the value, so that literals can be used as values of the sized integer
+// types without converting them. Elements of array literals are adapted to
+// the element type. The value has to fit into the type.
+func (r *Resolver) adapt(v ast.Value, t ast.Type) error {
+	if literal, ok := v.(*ast.ArrayLiteral); ok {
+		return r.adaptElements(literal, t)
+	}
+	// literals too big for int are u64, which only fits u64 itself
+	if literal, ok := v.(*ast.Literal); ok && ast.IsInteger(t) && !t.Equals(ast.BasicTypePtr(ast.U64)) {
+		if _, err := lexer.ParseIntLiteral(literal.Value); errors.Is(err, strconv.ErrRange) {
+			return typeResolutionError(v.GetPosition(), "constant %s overflows %v", literal.Value, t)
+		}
+	}
+	if !ast.IsInteger(t) || v.GetType() == nil || !v.GetType().Equals(ast.BasicTypePtr(ast.Int)) || !isConstantInt(v) {
+		return nil
+	}
+	value, err := r.evaluate(v)
+	if err != nil {
+		return err
+	}
+	if number := value.(int64); wrap(number, *t.(*ast.BasicType)) != number || (number < 0 && ast.IsUnsigned(t)) {
+		return typeResolutionError(v.GetPosition(), "constant %d overflows %v", number, t)
+	}
+	retype(v, t)
+	return nil
+}
+
+func (r *Resolver) adaptElements(literal *ast.ArrayLiteral, t ast.Type) error {
+	var element ast.Type
+	switch t := t.(type) {
+	case *ast.ArrayType:
+		element = t.Element
+	case *ast.SliceType:
+		element = t.Element
+	default:
+		return nil
+	}
+	if _, isArray := literal.GetType().(*ast.ArrayType); !isArray {
+		return nil
+	}
+
+	var err error
+	for _, value := range literal.Values {
+		err = errors.Join(err, r.adapt(value, element))
+	}
+	literal.SetType(&ast.ArrayType{
+		Element: literal.Values[0].GetType(),
+		Length:  len(literal.Values),
+	})
+	return err
+}
+
+// isConstantInt reports whether the int value is made of int literals only.
+func isConstantInt(v ast.Value) bool {
+	switch v := v.(type) {
+	case *ast.Literal:
+		return true
+	case *ast.Separated:
+		return isConstantInt(v.Value)
+	case *ast.Unary:
+		return v.Operator != ast.AddressOf && isConstantInt(v.Value)
+	case *ast.Binary:
+		return isConstantInt(v.Left) && isConstantInt(v.Right)
+	}
+	return false
+}
+
+// retype sets the type of the constant int value and all of its operands.
+func retype(v ast.Value, t ast.Type) {
+	switch v := v.(type) {
+	case *ast.Separated:
+		retype(v.Value, t)
+	case *ast.Unary:
+		retype(v.Value, t)
+	case *ast.Binary:
+		retype(v.Left, t)
+		retype(v.Right, t)
+	}
+	v.SetType(t)
+}
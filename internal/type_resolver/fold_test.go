@@ -0,0 +1,98 @@
+package type_resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MisustinIvan/ilang/internal/lexer"
+	"github.com/MisustinIvan/ilang/internal/name_resolver"
+	"github.com/MisustinIvan/ilang/internal/parser"
+)
+
+func TestFoldConstants(t *testing.T) {
+	input := `const A: u8 = 200;
+const B: u8 = A + 100;
+const C: u8 = A * 2;
+const E: i8 = -128;
+const F: i8 = E - 1;
+const D: i8 = F + 1;
+const U: u64 = 18446744073709551615;
+const H: u64 = U / 2;
+const M: u64 = U % 10;
+const S: u64 = U >> 60;
+const T: int = -16 >> 2;
+const G: bool = U > 5;
+const L: bool = U < 5;
+const X: u8 = ~A;
+const N: int = -9223372036854775808;
+const O: int = N - 1;
+int main() { 0 }`
+	expected := map[string]string{
+		"A": "200",
+		"B": "44",
+		"C": "144",
+		"D": "-128",
+		"E": "-128",
+		"F": "127",
+		"U": "-1",
+		"H": "9223372036854775807",
+		"M": "5",
+		"S": "15",
+		"T": "-4",
+		"G": "true",
+		"L": "false",
+		"X": "55",
+		"N": "-9223372036854775808",
+		"O": "9223372036854775807",
+	}
+
+	tokens, err := lexer.New(lexer.NewSourceFile("test", input)).Lex()
+	if err != nil {
+		t.Fatalf("Lexing failed: %v", err)
+	}
+	program, err := parser.New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parsing failed: %v", err)
+	}
+	if program, err = name_resolver.NewResolver(program).ResolveNames(); err != nil {
+		t.Fatalf("Name resolution failed: %v", err)
+	}
+	if program, err = NewResolver(program).ResolveTypes(); err != nil {
+		t.Fatalf("Type resolution failed: %v", err)
+	}
+
+	for _, constant := range program.Constants {
+		if constant.Folded.Value != expected[constant.Identifier.Name] {
+			t.Errorf("%s folded to %s, expected %s", constant.Identifier.Name, constant.Folded.Value, expected[constant.Identifier.Name])
+		}
+	}
+}
+
+func TestNegatedLiteralOverflow(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"const N: int = -9223372036854775809; int main() { 0 }", "constant -9223372036854775809 overflows Int"},
+		{"const N: int = -18446744073709551615; int main() { 0 }", "constant -18446744073709551615 overflows Int"},
+		{"const N: u64 = -9223372036854775808; int main() { 0 }", "constant -9223372036854775808 overflows U64"},
+		{"int main() { let x: i8 = -9223372036854775808; 0 }", "constant -9223372036854775808 overflows I8"},
+	}
+
+	for _, tt := range tests {
+		tokens, err := lexer.New(lexer.NewSourceFile("test", tt.input)).Lex()
+		if err != nil {
+			t.Fatalf("Lexing failed: %v", err)
+		}
+		program, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parsing failed: %v", err)
+		}
+		if program, err = name_resolver.NewResolver(program).ResolveNames(); err != nil {
+			t.Fatalf("Name resolution failed: %v", err)
+		}
+		if _, err = NewResolver(program).ResolveTypes(); err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+			t.Fatalf("Expected error %q on %q, got %v", tt.expectedError, tt.input, err)
+		}
+	}
+}
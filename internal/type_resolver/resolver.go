@@ -8,6 +8,7 @@ package type_resolver
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -20,22 +21,72 @@ func typeResolutionError(position lexer.Position, message string, args ...any) e
 }
 
 type Resolver struct {
-	prog *ast.Program
+	prog      *ast.Program
+	structs   map[*ast.Identifier]*ast.StructDeclaration
+	enums     map[*ast.Identifier]*ast.EnumDeclaration
+	numbered  map[*ast.EnumDeclaration]bool
+	constants map[*ast.Identifier]*ast.Constant
+	// declaration is the function whose body is being resolved
+	declaration *ast.Declaration
 }
 
 func NewResolver(prog *ast.Program) *Resolver {
-	return &Resolver{
-		prog: prog,
+	r := &Resolver{
+		prog:      prog,
+		structs:   make(map[*ast.Identifier]*ast.StructDeclaration),
+		enums:     make(map[*ast.Identifier]*ast.EnumDeclaration),
+		numbered:  make(map[*ast.EnumDeclaration]bool),
+		constants: make(map[*ast.Identifier]*ast.Constant),
 	}
+
+	for _, decl := range prog.StructDeclarations {
+		r.structs[decl.Identifier] = decl
+	}
+
+	for _, decl := range prog.EnumDeclarations {
+		r.enums[decl.Identifier] = decl
+	}
+
+	for _, constant := range prog.Constants {
+		r.constants[constant.Identifier] = constant
+	}
+
+	return r
 }
 
 func (r *Resolver) ResolveTypes() (*ast.Program, error) { return r.prog, r.VisitProgram(r.prog) }
 func (r *Resolver) VisitProgram(p *ast.Program) error {
 	var err error
+	// constants are folded in the order of their declaration before their
+	// values are needed by array types or other constants
+	for _, constant := range p.Constants {
+		err = errors.Join(err, constant.Accept(r))
+	}
+
+	for _, decl := range p.EnumDeclarations {
+		err = errors.Join(err, decl.Accept(r))
+	}
+
+	for _, decl := range p.StructDeclarations {
+		err = errors.Join(err, decl.Accept(r))
+	}
+
+	// the sizes of recursive structs are infinite, so they have to be rejected
+	// before anything asks for them
+	for _, decl := range p.StructDeclarations {
+		if containsStruct(decl, decl, map[*ast.StructDeclaration]bool{}) {
+			err = errors.Join(err, typeResolutionError(decl.Identifier.GetPosition(), "struct %s contains itself", decl.Identifier.Name))
+		}
+	}
+
 	for _, decl := range p.ExternalDeclarations {
 		err = errors.Join(err, decl.Accept(r))
 	}
 
+	for _, global := range p.Globals {
+		err = errors.Join(err, global.Accept(r))
+	}
+
 	for _, decl := range p.Declarations {
 		err = errors.Join(err, decl.Accept(r))
 	}
@@ -43,59 +94,252 @@ func (r *Resolver) VisitProgram(p *ast.Program) error {
 	return err
 }
 
-func (r *Resolver) VisitDeclaration(d *ast.Declaration) error {
-	var err error
+func (r *Resolver) VisitConstant(c *ast.Constant) error {
+	err := errors.Join(c.Type.Accept(r), c.Value.Accept(r))
+	c.Identifier.SetType(c.Type)
+	if err != nil {
+		return err
+	}
+	if err := r.adapt(c.Value, c.Type); err != nil {
+		return err
+	}
+
+	c.Folded, err = r.fold(c.Value)
+	return err
+}
+
+// VisitGlobal folds the value of globals of basic and enum types, globals of
+// the other types can only be zero-initialized, which is left for the type
+// checker.
+func (r *Resolver) VisitGlobal(g *ast.Global) error {
+	err := errors.Join(g.Type.Accept(r), g.Value.Accept(r))
+	g.Identifier.SetType(g.Type)
+	if !isScalar(g.Type) || err != nil {
+		return err
+	}
+	if err := r.adapt(g.Value, g.Type); err != nil {
+		return err
+	}
+
+	g.Folded, err = r.fold(g.Value)
+	return err
+}
+
+// isScalar reports whether values of type t are basic types or enums, which
+// can be computed at compile time.
+func isScalar(t ast.Type) bool {
+	switch t.(type) {
+	case *ast.BasicType, *ast.EnumType:
+		return true
+	}
+	return false
+}
 
-	d.Identifier.SetType(&d.Type)
+func (r *Resolver) VisitDeclaration(d *ast.Declaration) error {
+	err := d.Type.Accept(r)
 
 	for _, arg := range d.Args {
 		err = errors.Join(arg.Accept(r))
 	}
 
+	d.Identifier.SetType(functionType(d.Args, d.Type, false))
+
+	r.declaration = d
 	err = errors.Join(err, d.Body.Accept(r))
+	if d.Body.ImplicitReturn != nil {
+		err = errors.Join(err, r.adapt(d.Body.ImplicitReturn, d.Type))
+		d.Body.SetType(d.Body.ImplicitReturn.GetType())
+	}
 
 	return err
 }
 
 func (r *Resolver) VisitExternalDeclaration(d *ast.ExternalDeclaration) error {
-	var err error
-	d.Identifier.SetType(d.Type)
+	err := d.Type.Accept(r)
 
 	for _, arg := range d.Args {
 		err = errors.Join(arg.Accept(r))
 	}
 
+	d.Identifier.SetType(functionType(d.Args, d.Type, d.Variadic))
 	return err
 }
 
+// functionType is the type of the name of a declared function, which is a
+// pointer to the function when used as a value.
+func functionType(args []ast.Argument, returnType ast.Type, variadic bool) *ast.FunctionType {
+	parameters := []ast.Type{}
+	for _, arg := range args {
+		parameters = append(parameters, arg.Type)
+	}
+	return &ast.FunctionType{
+		Parameters: parameters,
+		Return:     returnType,
+		Variadic:   variadic,
+	}
+}
+
+func (r *Resolver) VisitStructDeclaration(d *ast.StructDeclaration) error {
+	var err error
+
+	d.Identifier.SetType(&ast.StructType{
+		Identifier:  d.Identifier,
+		Declaration: d,
+	})
+
+	for _, field := range d.Fields {
+		err = errors.Join(err, field.Type.Accept(r))
+		field.Identifier.SetType(field.Type)
+	}
+
+	return err
+}
+
+// VisitEnumDeclaration numbers the variants, counting up from the previous
+// variant unless the number is given by a constant value. Constants can refer
+// to the variants, so enums are numbered once when they are first needed.
+func (r *Resolver) VisitEnumDeclaration(d *ast.EnumDeclaration) error {
+	if r.numbered[d] {
+		return nil
+	}
+	r.numbered[d] = true
+	var err error
+
+	enumType := &ast.EnumType{
+		Identifier:  d.Identifier,
+		Declaration: d,
+	}
+	d.Identifier.SetType(enumType)
+
+	next := int64(0)
+	for i, variant := range d.Variants {
+		variant.Identifier.SetType(enumType)
+		if variant.Value != nil {
+			number, numberErr := r.foldInt(variant.Value)
+			if numberErr != nil {
+				err = errors.Join(err, numberErr)
+				continue
+			}
+			next = number
+		}
+		d.Variants[i].Number = next
+		next++
+	}
+
+	return err
+}
+
+// foldInt resolves the type of the value and computes it as a constant int.
+func (r *Resolver) foldInt(v ast.Value) (int64, error) {
+	if err := v.Accept(r); err != nil {
+		return 0, err
+	}
+	value, err := r.evaluate(v)
+	if err != nil {
+		return 0, err
+	}
+	number, ok := value.(int64)
+	if !ok {
+		return 0, typeResolutionError(v.GetPosition(), "value of type %v must be a constant int", v.GetType())
+	}
+	return number, nil
+}
+
+// containsStruct reports whether the struct d contains the target struct by
+// value, either directly or through any of its fields.
+func containsStruct(d, target *ast.StructDeclaration, visited map[*ast.StructDeclaration]bool) bool {
+	if visited[d] {
+		return false
+	}
+	visited[d] = true
+
+	for _, field := range d.Fields {
+		if structType, ok := field.Type.(*ast.StructType); ok && structType.Declaration != nil {
+			if structType.Declaration == target || containsStruct(structType.Declaration, target, visited) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (r *Resolver) VisitArgument(a *ast.Argument) error {
 	err := a.Type.Accept(r)
 	a.Identifier.SetType(a.Type)
 	return err
 }
 
-func (r *Resolver) VisitBasicType(t *ast.BasicType) error     { return nil }
-func (r *Resolver) VisitArrayType(t *ast.ArrayType) error     { return nil }
-func (r *Resolver) VisitPointerType(t *ast.PointerType) error { return nil }
+func (r *Resolver) VisitBasicType(t *ast.BasicType) error { return nil }
+func (r *Resolver) VisitArrayType(t *ast.ArrayType) error {
+	if t.Constant != nil {
+		constant := r.constants[t.Constant.Resolved]
+		if constant.Folded == nil || !constant.Folded.GetType().Equals(ast.BasicTypePtr(ast.Int)) {
+			return typeResolutionError(t.Constant.Position, "array length %s must be a constant of type int", t.Constant.Name)
+		}
+		length, _ := lexer.ParseIntLiteral(constant.Folded.Value)
+		if length < 0 {
+			return typeResolutionError(t.Constant.Position, "array length %s must not be negative, got %d", t.Constant.Name, length)
+		}
+		t.Length = int(length)
+	}
+	return t.Element.Accept(r)
+}
+func (r *Resolver) VisitPointerType(t *ast.PointerType) error { return t.Inner.Accept(r) }
+func (r *Resolver) VisitStructType(t *ast.StructType) error {
+	decl, ok := r.structs[t.Identifier.Resolved]
+	if !ok {
+		return typeResolutionError(t.Identifier.GetPosition(), "%s is not a struct type", t.Identifier.Name)
+	}
+	t.Declaration = decl
+	return nil
+}
+func (r *Resolver) VisitEnumType(t *ast.EnumType) error {
+	decl, ok := r.enums[t.Identifier.Resolved]
+	if !ok {
+		return typeResolutionError(t.Identifier.GetPosition(), "%s is not an enum type", t.Identifier.Name)
+	}
+	t.Declaration = decl
+	return nil
+}
+func (r *Resolver) VisitFunctionType(t *ast.FunctionType) error {
+	var err error
+	for _, parameter := range t.Parameters {
+		err = errors.Join(err, parameter.Accept(r))
+	}
+	return errors.Join(err, t.Return.Accept(r))
+}
 func (r *Resolver) VisitSliceType(t *ast.SliceType) error {
 	if t.LengthIdentifier != nil {
 		t.LengthIdentifier.SetType(ast.BasicTypePtr(ast.Int))
 	}
-	return nil
+	return t.Element.Accept(r)
 }
 
 func (r *Resolver) VisitReturn(e *ast.Return) error {
 	var err error
 	err = errors.Join(err, e.Value.Accept(r))
+	err = errors.Join(err, r.adapt(e.Value, r.declaration.Type))
 	e.SetType(e.Value.GetType())
 	return err
 }
 
+func (r *Resolver) VisitBreak(b *ast.Break) error {
+	b.SetType(ast.BasicTypePtr(ast.Unit))
+	return nil
+}
+
+func (r *Resolver) VisitContinue(c *ast.Continue) error {
+	c.SetType(ast.BasicTypePtr(ast.Unit))
+	return nil
+}
+
 func (r *Resolver) VisitBind(b *ast.Bind) error {
 	err := b.Type.Accept(r)
 	b.Identifier.SetType(b.Type)
 	b.SetType(b.Type)
-	return errors.Join(err, b.Value.Accept(r))
+	err = errors.Join(err, b.Value.Accept(r))
+	return errors.Join(err, r.adapt(b.Value, b.Type))
 }
 
 func literalType(val string) *ast.BasicType {
@@ -107,9 +351,15 @@ func literalType(val string) *ast.BasicType {
 		t = ast.Bool
 	} else if strings.HasPrefix(val, "\"") {
 		t = ast.String
-	} else if _, err := strconv.ParseInt(val, 10, 64); err == nil {
-		t = ast.Int
-	} else if _, err := strconv.ParseFloat(val, 64); err == nil {
+	} else if _, err := lexer.ParseIntLiteral(val); !errors.Is(err, strconv.ErrSyntax) {
+		// literals too big for int are u64, the ones out of range even for
+		// u64 stay undefined
+		if err == nil {
+			t = ast.Int
+		} else if _, err := lexer.ParseUnsignedLiteral(val); err == nil {
+			t = ast.U64
+		}
+	} else if _, err := lexer.ParseFloatLiteral(val); err == nil {
 		t = ast.Float
 	}
 
@@ -120,6 +370,12 @@ func (r *Resolver) VisitLiteral(l *ast.Literal) error {
 	t := literalType(l.Value)
 	l.SetType(t)
 	if t.Equals(ast.BasicTypePtr(ast.Undefined)) {
+		if _, err := lexer.ParseUnsignedLiteral(l.Value); errors.Is(err, strconv.ErrRange) {
+			return typeResolutionError(l.GetPosition(), "integer literal %s overflows u64", l.Value)
+		}
+		if _, err := lexer.ParseFloatLiteral(l.Value); errors.Is(err, strconv.ErrRange) {
+			return typeResolutionError(l.GetPosition(), "float literal %s overflows float", l.Value)
+		}
 		return typeResolutionError(l.GetPosition(), "literal of undefined type")
 	}
 	return nil
@@ -136,16 +392,22 @@ func (r *Resolver) VisitIdentifier(i *ast.Identifier) error {
 
 func (r *Resolver) VisitCall(c *ast.Call) error {
 	var err error
-	err = errors.Join(err, c.Identifier.Accept(r))
-	if c.Identifier.Resolved == nil {
+	err = errors.Join(err, c.Function.Accept(r))
+	if identifier, ok := c.Function.(*ast.Identifier); ok && identifier.Resolved == nil {
 		c.SetType(ast.BasicTypePtr(ast.Undefined))
 		err = errors.Join(err, typeResolutionError(c.GetPosition(), "unresolved function call has undefined type"))
+	} else if functionType, ok := c.Function.GetType().(*ast.FunctionType); ok {
+		c.SetType(functionType.Return)
 	} else {
-		c.SetType(c.Identifier.Resolved.Type)
+		c.SetType(ast.BasicTypePtr(ast.Undefined))
+		err = errors.Join(err, typeResolutionError(c.GetPosition(), "can't call value of type %v", c.Function.GetType()))
 	}
 
-	for _, arg := range c.Arguments {
+	for i, arg := range c.Arguments {
 		err = errors.Join(err, arg.Accept(r))
+		if functionType, ok := c.Function.GetType().(*ast.FunctionType); ok && i < len(functionType.Parameters) {
+			err = errors.Join(err, r.adapt(arg, functionType.Parameters[i]))
+		}
 	}
 
 	return err
@@ -160,14 +422,18 @@ func (r *Resolver) VisitSeparated(s *ast.Separated) error {
 func (r *Resolver) VisitUnary(u *ast.Unary) error {
 	err := u.Value.Accept(r)
 
-	if u.Operator == ast.AddressOf {
-		inner, ok := u.Value.GetType().(*ast.BasicType)
-		if !ok {
-			return typeResolutionError(u.GetPosition(), "can't take address of %s, can only take address of basic types", u.Value.GetType().String())
+	// a negated literal is a negative int, so only the magnitude of the
+	// smallest int may be too big for int
+	if literal, ok := u.Value.(*ast.Literal); ok && u.Operator == ast.Inversion && literal.GetType().Equals(ast.BasicTypePtr(ast.U64)) {
+		if number, _ := lexer.ParseUnsignedLiteral(literal.Value); number != math.MinInt64 {
+			err = errors.Join(err, typeResolutionError(u.GetPosition(), "constant -%s overflows %v", literal.Value, ast.Int))
 		}
+		literal.SetType(ast.BasicTypePtr(ast.Int))
+	}
 
+	if u.Operator == ast.AddressOf {
 		u.SetType(&ast.PointerType{
-			Inner: inner,
+			Inner: u.Value.GetType(),
 		})
 	} else {
 		u.SetType(u.Value.GetType())
@@ -180,6 +446,7 @@ func (r *Resolver) VisitBinary(u *ast.Binary) error {
 	var err error
 	err = errors.Join(err, u.Left.Accept(r))
 	err = errors.Join(err, u.Right.Accept(r))
+	err = errors.Join(err, r.adapt(u.Left, u.Right.GetType()), r.adapt(u.Right, u.Left.GetType()))
 	if ast.BoolOperators[u.Operator] {
 		u.SetType(ast.BasicTypePtr(ast.Bool))
 	} else {
@@ -223,6 +490,7 @@ func (r *Resolver) VisitAssignment(a *ast.Assignment) error {
 
 	err = errors.Join(err, a.Target.Accept(r))
 	err = errors.Join(err, a.Value.Accept(r))
+	err = errors.Join(err, r.adapt(a.Value, a.Target.GetType()))
 	a.SetType(a.Target.GetType())
 
 	return err
@@ -245,11 +513,50 @@ func (r *Resolver) VisitLoop(l *ast.Loop) error {
 }
 
 func (r *Resolver) VisitMake(m *ast.Make) error {
+	err := m.Type.Accept(r)
+	if m.Length == nil {
+		m.SetType(m.Type)
+		return err
+	}
 	m.SetType(&ast.SliceType{
 		Element:          m.Type,
 		LengthIdentifier: nil,
 	})
-	return m.Length.Accept(r)
+	return errors.Join(err, m.Length.Accept(r))
+}
+
+func (r *Resolver) VisitConversion(c *ast.Conversion) error {
+	c.SetType(c.Type)
+	return errors.Join(c.Type.Accept(r), c.Value.Accept(r))
+}
+
+func (r *Resolver) VisitLength(l *ast.Length) error {
+	l.SetType(ast.BasicTypePtr(ast.Int))
+	return l.Value.Accept(r)
+}
+
+func (r *Resolver) VisitAppend(a *ast.Append) error {
+	err := errors.Join(a.Slice.Accept(r), a.Value.Accept(r))
+
+	a.SetType(ast.BasicTypePtr(ast.Undefined))
+	if a.Slice.GetType() == nil {
+		return err
+	}
+
+	if t, isArray := a.Slice.GetType().(*ast.ArrayType); isArray {
+		a.SetType(&ast.SliceType{Element: t.Element})
+	} else if t, isSlice := a.Slice.GetType().(*ast.SliceType); isSlice {
+		a.SetType(&ast.SliceType{Element: t.Element})
+	} else {
+		return errors.Join(err, typeResolutionError(a.GetPosition(), "appending to value of non-array/slice type %s", a.Slice.GetType()))
+	}
+
+	return errors.Join(err, r.adapt(a.Value, a.GetType().(*ast.SliceType).Element))
+}
+
+func (r *Resolver) VisitCopy(c *ast.Copy) error {
+	c.SetType(ast.BasicTypePtr(ast.Int))
+	return errors.Join(c.Destination.Accept(r), c.Source.Accept(r))
 }
 
 func (r *Resolver) VisitRelease(s *ast.Release) error {
@@ -269,16 +576,16 @@ func (r *Resolver) VisitArrayLiteral(a *ast.ArrayLiteral) error {
 		err = errors.Join(err, val.Accept(r))
 	}
 
-	// Infer type from the first element
+	// Infer type from the first element, slices can't be elements of array
+	// literals as their values are not copied
 	elementType := a.Values[0].GetType()
-	if basicType, ok := elementType.(*ast.BasicType); ok {
+	if _, isSlice := elementType.(*ast.SliceType); isSlice {
+		a.SetType(ast.BasicTypePtr(ast.Undefined))
+	} else {
 		a.SetType(&ast.ArrayType{
-			Element: *basicType,
+			Element: elementType,
 			Length:  len(a.Values),
 		})
-	} else {
-		// nested arrays or arrays of complex types(in the future) are not supported yet
-		a.SetType(ast.BasicTypePtr(ast.Undefined))
 	}
 
 	return err
@@ -287,15 +594,114 @@ func (r *Resolver) VisitArrayLiteral(a *ast.ArrayLiteral) error {
 func (r *Resolver) VisitIndex(i *ast.Index) error {
 	var err error
 
-	err = errors.Join(err, i.Identifier.Accept(r))
+	err = errors.Join(err, i.Value.Accept(r))
 	err = errors.Join(err, i.Index.Accept(r))
 
-	if t, isArray := i.Identifier.Resolved.GetType().(*ast.ArrayType); isArray {
-		i.SetType(&t.Element)
-	} else if t, isSlice := i.Identifier.Resolved.GetType().(*ast.SliceType); isSlice {
-		i.SetType(&t.Element)
+	i.SetType(ast.BasicTypePtr(ast.Undefined))
+	if i.Value.GetType() == nil {
+		return err
+	}
+
+	if t, isArray := i.Value.GetType().(*ast.ArrayType); isArray {
+		i.SetType(t.Element)
+	} else if t, isSlice := i.Value.GetType().(*ast.SliceType); isSlice {
+		i.SetType(t.Element)
+	} else if i.Value.GetType().Equals(ast.BasicTypePtr(ast.String)) {
+		i.SetType(ast.BasicTypePtr(ast.U8)) // the bytes of the string
+	} else {
+		return errors.Join(err, typeResolutionError(i.GetPosition(), "indexing value of non-array/slice/string type %s", i.Value.GetType()))
+	}
+
+	return err
+}
+
+func (r *Resolver) VisitSlice(s *ast.Slice) error {
+	err := s.Value.Accept(r)
+	if s.Low != nil {
+		err = errors.Join(err, s.Low.Accept(r))
+	}
+	if s.High != nil {
+		err = errors.Join(err, s.High.Accept(r))
+	}
+
+	s.SetType(ast.BasicTypePtr(ast.Undefined))
+	if s.Value.GetType() == nil {
+		return err
+	}
+
+	if t, isArray := s.Value.GetType().(*ast.ArrayType); isArray {
+		s.SetType(&ast.SliceType{Element: t.Element})
+	} else if t, isSlice := s.Value.GetType().(*ast.SliceType); isSlice {
+		s.SetType(&ast.SliceType{Element: t.Element})
 	} else {
-		return typeResolutionError(i.GetPosition(), "indexing identifier of non-array/slice type")
+		return errors.Join(err, typeResolutionError(s.GetPosition(), "slicing value of non-array/slice type %s", s.Value.GetType()))
+	}
+
+	return err
+}
+
+func (r *Resolver) VisitFieldAccess(f *ast.FieldAccess) error {
+	err := f.Value.Accept(r)
+
+	f.SetType(ast.BasicTypePtr(ast.Undefined))
+	if f.Value.GetType() == nil {
+		return err
+	}
+
+	structType, ok := f.Value.GetType().(*ast.StructType)
+	if !ok || structType.Declaration == nil {
+		return errors.Join(err, typeResolutionError(f.GetPosition(), "accessing field %s of non-struct type %s", f.Field.Name, f.Value.GetType().String()))
+	}
+
+	field, _ := structType.Declaration.Field(f.Field.Name)
+	if field == nil {
+		return errors.Join(err, typeResolutionError(f.Field.GetPosition(), "struct %s has no field %s", structType.String(), f.Field.Name))
+	}
+
+	f.Field.SetType(field.Type)
+	f.SetType(field.Type)
+	return err
+}
+
+func (r *Resolver) VisitVariantAccess(v *ast.VariantAccess) error {
+	v.SetType(ast.BasicTypePtr(ast.Undefined))
+	decl, ok := r.enums[v.Enum.Resolved]
+	if !ok {
+		return typeResolutionError(v.GetPosition(), "%s is not an enum type", v.Enum.Name)
+	}
+	if err := decl.Accept(r); err != nil {
+		return err
+	}
+
+	v.SetType(decl.Identifier.Type)
+	if decl.Variant(v.Variant.Name) == nil {
+		return typeResolutionError(v.Variant.GetPosition(), "enum %s has no variant %s", decl.Identifier.Name, v.Variant.Name)
+	}
+	v.Variant.SetType(decl.Identifier.Type)
+	return nil
+}
+
+// VisitMatch folds the patterns of the arms to the cases they match, the type
+// of the match is the type of its first arm.
+func (r *Resolver) VisitMatch(m *ast.Match) error {
+	err := m.Value.Accept(r)
+
+	for i, arm := range m.Arms {
+		m.Arms[i].Cases = make([]int64, len(arm.Patterns))
+		for j, pattern := range arm.Patterns {
+			number, patternErr := r.foldInt(pattern)
+			if patternErr == nil {
+				patternErr = r.adapt(pattern, m.Value.GetType())
+			}
+			err = errors.Join(err, patternErr)
+			m.Arms[i].Cases[j] = number
+		}
+		err = errors.Join(err, arm.Body.Accept(r))
+	}
+	m.SetType(m.Arms[0].Body.GetType())
+
+	if m.Else != nil {
+		err = errors.Join(err, m.Else.Accept(r))
 	}
 
 	return err
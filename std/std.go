@@ -0,0 +1,9 @@
+// Package std bundles the standard library modules with the compiler. They are
+// imported by paths starting with std/, like import "std/io", and don't need to
+// be present on the disk.
+package std
+
+import "embed"
+
+//go:embed *.ilang
+var Modules embed.FS
@@ -0,0 +1,118 @@
+package std_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/MisustinIvan/ilang/internal/code_generator"
+	"github.com/MisustinIvan/ilang/internal/lifetime_checker"
+	"github.com/MisustinIvan/ilang/internal/module_loader"
+	"github.com/MisustinIvan/ilang/internal/name_resolver"
+	"github.com/MisustinIvan/ilang/internal/type_checker"
+	"github.com/MisustinIvan/ilang/internal/type_resolver"
+)
+
+// run compiles the program testdata/<name>.ilang, runs it with the input and
+// prints its output.
+func run(name, input string) {
+	output, err := compileAndRun(filepath.Join("testdata", name+".ilang"), input)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(output)
+}
+
+func compileAndRun(path, input string) (string, error) {
+	program, err := module_loader.New(path).Load()
+	if err != nil {
+		return "", err
+	}
+	if program, err = name_resolver.NewResolver(program).ResolveNames(); err != nil {
+		return "", err
+	}
+	if program, err = type_resolver.NewResolver(program).ResolveTypes(); err != nil {
+		return "", err
+	}
+	if program, err = type_checker.NewChecker(program).CheckTypes(); err != nil {
+		return "", err
+	}
+	lifetimeChecker := lifetime_checker.NewChecker(program)
+	if program, err = lifetimeChecker.CheckLifetimes(); err != nil {
+		return "", err
+	}
+	if warnings := lifetimeChecker.Warnings(); len(warnings) > 0 {
+		return "", warnings[0]
+	}
+	assembly, err := code_generator.New(program).Generate()
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "ilang-std-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	asmFile := filepath.Join(dir, "program.s")
+	if err := os.WriteFile(asmFile, []byte(assembly), 0o644); err != nil {
+		return "", err
+	}
+	binary := filepath.Join(dir, "program")
+	if out, err := exec.Command("gcc", "-no-pie", "-o", binary, asmFile, "-lm").CombinedOutput(); err != nil {
+		return "", fmt.Errorf("gcc: %v\n%s", err, out)
+	}
+
+	cmd := exec.Command(binary)
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("run: %v", err)
+	}
+	return string(out), nil
+}
+
+func Example_io() {
+	run("io", "first\nsecond\n")
+	// Output:
+	// 1: first
+	// 2: second
+	// 1.5
+	// true
+}
+
+func Example_math() {
+	run("math", "")
+	// Output:
+	// 4
+	// 1024
+	// 7
+	// -2
+	// 3
+	// 10
+	// 3
+}
+
+func Example_mem() {
+	run("mem", "")
+	// Output:
+	// xxxxx
+	// hello
+	// true
+	// 14
+}
+
+func Example_slices() {
+	run("slices", "")
+	// Output:
+	// -7 -3 1 4 5 9
+	// 9 5 4 1 -3 -7
+	// 9
+	// 3
+	// 3.75
+	// 1 2 3
+}
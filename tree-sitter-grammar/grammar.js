@@ -8,14 +8,25 @@ module.exports = grammar({
 
 	rules: {
 		program: $ => repeat(choice(
+			$.import,
+			$.global,
+			$.constant,
 			$.declaration,
 			$.external_declaration,
+			$.struct_declaration,
+			$.enum_declaration,
 		)),
 
 		comment: $ => seq('#', /.*/),
 
+		import: $ => seq('import', $.string_literal),
+
+		global: $ => seq($.bind, ';'),
+
+		constant: $ => seq('const', $.identifier, ':', $.type, '=', $.value, ';'),
+
 		declaration: $ => seq(
-			$.basic_type,
+			$.type,
 			field('name', $.identifier),
 			'(',
 			optional(seq($.function_argument, repeat(seq(',', $.function_argument)))),
@@ -25,7 +36,7 @@ module.exports = grammar({
 
 		external_declaration: $ => seq(
 			'extrn',
-			$.basic_type,
+			$.type,
 			$.identifier,
 			'(',
 			choice(
@@ -37,6 +48,28 @@ module.exports = grammar({
 
 		function_argument: $ => seq($.type, $.identifier),
 
+		struct_declaration: $ => seq(
+			'struct',
+			field('name', $.identifier),
+			'{',
+			repeat($.struct_field),
+			'}'
+		),
+
+		struct_field: $ => seq($.type, $.identifier, ';'),
+
+		enum_declaration: $ => seq(
+			'enum',
+			field('name', $.identifier),
+			'{',
+			$.enum_variant,
+			repeat(seq(',', $.enum_variant)),
+			optional(','),
+			'}'
+		),
+
+		enum_variant: $ => seq($.identifier, optional(seq('=', $.value))),
+
 		block: $ => seq(
 			'{',
 			repeat(seq($.expression, ';')),
@@ -46,6 +79,8 @@ module.exports = grammar({
 
 		expression: $ => choice(
 			$.return,
+			$.break,
+			$.continue,
 			$.bind,
 			$.assignment,
 			$.value
@@ -58,21 +93,27 @@ module.exports = grammar({
 		),
 
 		return: $ => seq('return', $.value),
+		break: $ => 'break',
+		continue: $ => 'continue',
 		bind: $ => seq('let', $.identifier, ':', $.type, '=', $.value),
 
 		assignment: $ => seq(
-			choice($.identifier, $.index, $.deref),
+			choice($.identifier, $.index, $.deref, $.field_access),
 			'=',
 			$.value
 		),
 
-		deref: $ => prec(1, seq('@', $.identifier)),
+		deref: $ => prec(4, seq('@', $.primary)),
 
 		binary: $ => prec(1, prec.left(seq($.primary, $.binary_operator, $.value))),
 
 		unary: $ => prec(2, seq($.unary_operator, $.primary)),
 
-		index: $ => prec(1, seq($.identifier, '[', $.primary, ']')),
+		index: $ => prec.left(3, seq($.primary, '[', $.value, ']')),
+
+		slice: $ => prec.left(3, seq($.primary, '[', optional(field('low', $.value)), ':', optional(field('high', $.value)), ']')),
+
+		field_access: $ => prec.left(3, seq($.primary, '.', field('field', $.identifier))),
 
 		primary: $ => choice(
 			$.literal,
@@ -82,16 +123,29 @@ module.exports = grammar({
 			$.block,
 			$.condition,
 			$.index,
+			$.slice,
 			$.deref,
 			$.loop,
 			$.make,
-			$.release
+			$.release,
+			$.field_access,
+			$.conversion,
+			$.length,
+			$.append,
+			$.copy,
+			$.variant_access,
+			$.match
 		),
 
-		make: $ => seq('make', '(', $.basic_type, ',', $.value, ')'),
-		release: $ => seq('release', '(', $.identifier, ')'),
+		conversion: $ => prec(2, seq(choice($.integer_type, 'float', 'bool', 'string', seq('[', ']', $.type)), '(', $.value, ')')),
+		length: $ => prec(2, seq('len', '(', $.value, ')')),
+		append: $ => prec(2, seq('append', '(', $.value, ',', $.value, ')')),
+		copy: $ => prec(2, seq('copy', '(', $.value, ',', $.value, ')')),
+
+		make: $ => seq('make', '(', $.type, optional(seq(',', $.value)), ')'),
+		release: $ => seq('release', '(', $.value, ')'),
 		loop: $ => seq('for', $.value, $.block),
-		call: $ => prec(1, seq($.identifier, '(', optional(seq($.value, repeat(seq(',', $.value)))), ')')),
+		call: $ => prec.left(3, seq($.primary, '(', optional(seq($.value, repeat(seq(',', $.value)))), ')')),
 		separated: $ => seq('(', $.value, ')'),
 
 		condition: $ => prec.right(seq(
@@ -99,22 +153,45 @@ module.exports = grammar({
 			optional(seq('else', $.value))
 		)),
 
-		type: $ => choice($.basic_type, $.array_type, $.slice_type, $.pointer_type),
-		basic_type: $ => choice('int', 'bool', 'float', 'string', 'unit'),
-		array_type: $ => seq('[', $.int_literal, ']', $.basic_type),
-		slice_type: $ => seq('[', optional($.identifier), ']', $.basic_type),
-		pointer_type: $ => seq('^', $.basic_type),
+		variant_access: $ => seq(field('enum', $.identifier), '::', field('variant', $.identifier)),
+
+		match: $ => seq(
+			'match', $.value, '{',
+			$.match_arm,
+			repeat(seq(',', $.match_arm)),
+			optional(','),
+			'}'
+		),
+
+		match_arm: $ => seq(
+			choice(seq($.pattern, repeat(seq('|', $.pattern))), 'else'),
+			'=>',
+			$.value
+		),
 
-		literal: $ => choice($.int_literal, $.float_literal, $.string_literal, $.bool_literal, $.array_literal),
-		int_literal: $ => /\d+/,
-		float_literal: $ => /\d+\.\d*/,
-		string_literal: $ => /"[^"]*"/,
+		pattern: $ => choice($.primary, $.unary),
+
+		type: $ => choice($.basic_type, $.array_type, $.slice_type, $.pointer_type, $.function_type, $.struct_type),
+		basic_type: $ => choice($.integer_type, 'bool', 'float', 'string', 'unit'),
+		integer_type: $ => choice('int', 'i8', 'i16', 'i32', 'u8', 'u16', 'u32', 'u64'),
+		array_type: $ => seq('[', $.int_literal, ']', $.type),
+		slice_type: $ => seq('[', optional($.identifier), ']', $.type),
+		pointer_type: $ => seq('^', $.type),
+		function_type: $ => seq('fn', '(', optional(seq($.type, repeat(seq(',', $.type)))), ')', $.type),
+		struct_type: $ => $.identifier,
+
+		literal: $ => choice($.int_literal, $.float_literal, $.string_literal, $.char_literal, $.bool_literal, $.array_literal),
+		int_literal: $ => token(choice(/\d(_?\d)*/, /0x[0-9a-fA-F](_?[0-9a-fA-F])*/, /0b[01](_?[01])*/, /0o[0-7](_?[0-7])*/)),
+		float_literal: $ => /\d(_?\d)*(\.(\d(_?\d)*)?([eE][+-]?\d(_?\d)*)?|[eE][+-]?\d(_?\d)*)/,
+		string_literal: $ => seq('"', repeat(choice(token.immediate(prec(1, /[^"\\]+/)), $.escape_sequence)), '"'),
+		char_literal: $ => seq("'", choice(token.immediate(/[^'\\]/), $.escape_sequence), "'"),
+		escape_sequence: $ => token.immediate(/\\(x[0-9a-fA-F]{2}|u\{[0-9a-fA-F]+\}|[nrt0\\"'])/),
 		bool_literal: $ => choice('true', 'false'),
 		array_literal: $ => seq('[', optional(seq($.value, repeat(seq(',', $.value)))), ']'),
 
 		identifier: $ => /[a-zA-Z_][a-zA-Z0-9_]*/,
 
-		binary_operator: $ => choice('+', '-', '*', '/', '==', '!=', '<', '>', '<=', '>=', '<<', '>>', '&&', '||'),
-		unary_operator: $ => choice('-', '!', '^', '@'),
+		binary_operator: $ => choice('+', '-', '*', '/', '%', '==', '!=', '<', '>', '<=', '>=', '<<', '>>', '&&', '||', '&', '|', '^'),
+		unary_operator: $ => choice('-', '!', '^', '@', '~'),
 	}
 });
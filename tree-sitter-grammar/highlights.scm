@@ -1,19 +1,31 @@
 ; Keywords
-["return" "let" "extrn" "if" "else" "for" "make" "release"] @keyword
+["return" "let" "extrn" "if" "else" "for" "make" "release" "struct" "import" "break" "continue" "const" "enum" "match" "fn"] @keyword
 
 ; Built-in Types
 (basic_type) @type
+(struct_type) @type
+(struct_declaration name: (identifier) @type)
+(enum_declaration name: (identifier) @type)
+(variant_access enum: (identifier) @type)
+(variant_access variant: (identifier) @constant)
+(enum_variant (identifier) @constant)
 (pointer_type "^" @type.punctuation)
 (array_type ["[" "]" ] @punctuation.bracket)
 
 ; Functions
 (declaration name: (identifier) @function)
 (call (identifier) @function.call)
+(conversion [(integer_type) "float" "bool" "string"] @function.builtin)
+(length "len" @function.builtin)
+(append "append" @function.builtin)
+(copy "copy" @function.builtin)
 
 ; Literals
 (int_literal) @number
 (float_literal) @number.float
 (string_literal) @string
+(char_literal) @character
+(escape_sequence) @string.escape
 (bool_literal) @boolean
 (comment) @comment
 
@@ -21,9 +33,12 @@
 (binary_operator) @operator
 (unary_operator) @operator
 ["(" ")" "[" "]" "{" "}"] @punctuation.bracket
-["," ";" ":" "="] @punctuation.delimiter
+["," ";" ":" "=" "." "::" "=>"] @punctuation.delimiter
 
 ; Variables
+(field_access field: (identifier) @property)
+(struct_field (identifier) @property)
 (identifier) @variable
 (function_argument (identifier) @variable.parameter)
 (bind (identifier) @variable.declaration)
+(constant (identifier) @constant)